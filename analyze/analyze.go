@@ -0,0 +1,111 @@
+// Analyze reports how each player in a set of hand histories played: their
+// winnings in milli big blinds per hand with a 95% confidence interval, VPIP,
+// PFR, aggression factor, how often they fold to a continuation bet, and how
+// often they go to and win at the showdown, overall and by position, and then
+// their actions in each round. It reads the files given, or the standard
+// input if there are none.
+//
+//	analyze [flags] [file ...]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"poker/game"
+	"poker/game/diff"
+	"poker/game/stats"
+)
+
+// pct formats a percentage, or "-" if there was nothing to count.
+func pct(n, total int) string {
+	return num(stats.Percent(n, total))
+}
+
+func num(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", f)
+}
+
+func row(w io.Writer, name string, c *stats.Counts) {
+	mbb, ci := c.MBB()
+	fmt.Fprintf(w, "%s\t%d\t%.0f ± %.0f\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", name, c.Hands, mbb, ci,
+		pct(c.VPIP, c.Hands), pct(c.PFR, c.Hands), num(c.AF(-1)),
+		pct(c.CbetFolded, c.CbetFaced), pct(c.SawFlop, c.Hands),
+		pct(c.Showdowns, c.SawFlop), pct(c.ShowdownsWon, c.Showdowns))
+}
+
+func report(w io.Writer, s *stats.Stats, r *game.Rules) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "player\thands\tmbb/hand\tVPIP\tPFR\tAF\tFtCB\tflop\tWTSD\tW$SD")
+	for _, name := range s.Players {
+		row(tw, name, s.All[name])
+		for _, position := range stats.Positions {
+			if c, ok := s.ByPosition[name][position]; ok {
+				row(tw, "  "+position, c)
+			}
+		}
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "player\tround\traise %\tcall %\tcheck %\tfold %\tAF")
+	for _, name := range s.Players {
+		c := s.All[name]
+		for i := 0; i < r.NumRounds() && i < len(game.RoundNames); i++ {
+			total := c.Raises[i] + c.Calls[i] + c.Checks[i] + c.Folds[i]
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", name, game.RoundNames[i],
+				pct(c.Raises[i], total), pct(c.Calls[i], total), pct(c.Checks[i], total),
+				pct(c.Folds[i], total), num(c.AF(i)))
+		}
+	}
+	tw.Flush()
+}
+
+func main() {
+	rules := flag.String("rules", "2p-l", "What rules the hands were played with: "+
+		strings.Join(game.RulesNames(), ", ")+", or an ACPC .game file.")
+	from := flag.String("from", "acpc", "The format to read: "+diff.HistoryFormats+".")
+	flag.Parse()
+	r, err := game.ChooseRules(*rules)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	var inputs []io.Reader
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		inputs = append(inputs, f)
+	}
+	if len(inputs) == 0 {
+		inputs = append(inputs, os.Stdin)
+	}
+	s := stats.New(r)
+	for _, in := range inputs {
+		hands, err := diff.NewHandReader(*from, in)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for {
+			h, err := hands.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				log.Fatalln(err)
+			}
+			if err := s.Add(h); err != nil {
+				log.Fatalln(err)
+			}
+		}
+	}
+	report(os.Stdout, s, r)
+}
@@ -0,0 +1,132 @@
+package cards
+
+import "strings"
+
+// Hand categories returned in the high bits of Rank, numbered as in the
+// 2 + 2 hand ranks table.
+const (
+	HighCard = iota + 1
+	Pair
+	TwoPair
+	ThreeOfAKind
+	Straight
+	Flush
+	FullHouse
+	FourOfAKind
+	StraightFlush
+)
+
+// Rank returns the value of the best poker hand that can be made from any
+// number of cards, so that a better hand has a greater value. It works for
+// small decks and for hands of fewer than five cards, which can only be high
+// card, pair, two pair, three or four of a kind. The category of the hand is
+// Rank >> 20.
+func Rank(hand []string) int32 {
+	var counts [len(Ranks)]int
+	var suits [len(Suits)]uint16 // A bit set of the ranks in each suit.
+	var all uint16
+	for _, c := range hand {
+		r := strings.IndexByte(Ranks, c[0])
+		s := strings.IndexByte(Suits, c[1])
+		counts[r]++
+		suits[s] |= 1 << uint(r)
+		all |= 1 << uint(r)
+	}
+	best := int32(0)
+	for _, s := range suits {
+		if n := popCount(s); n >= 5 {
+			if high := straightHigh(s); high >= 0 {
+				return value(StraightFlush, high)
+			}
+			if v := value(Flush, topRanks(s, 5)...); v > best {
+				best = v
+			}
+		}
+	}
+	quads, trips, pairs := -1, -1, []int(nil)
+	for r := len(Ranks) - 1; r >= 0; r-- {
+		switch {
+		case counts[r] == 4 && quads < 0:
+			quads = r
+		case counts[r] >= 3 && trips < 0:
+			trips = r
+		case counts[r] >= 2:
+			pairs = append(pairs, r)
+		}
+	}
+	switch {
+	case quads >= 0:
+		return value(FourOfAKind, append([]int{quads}, kickers(all, 1, quads)...)...)
+	case trips >= 0 && len(pairs) > 0:
+		return value(FullHouse, trips, pairs[0])
+	case best > 0:
+		return best
+	}
+	if high := straightHigh(all); high >= 0 {
+		return value(Straight, high)
+	}
+	switch {
+	case trips >= 0:
+		return value(ThreeOfAKind, append([]int{trips}, kickers(all, 2, trips)...)...)
+	case len(pairs) > 1:
+		return value(TwoPair, append(pairs[:2], kickers(all, 1, pairs[0], pairs[1])...)...)
+	case len(pairs) == 1:
+		return value(Pair, append([]int{pairs[0]}, kickers(all, 3, pairs[0])...)...)
+	}
+	return value(HighCard, topRanks(all, 5)...)
+}
+
+// value packs a category and up to five ranks into a hand value.
+func value(category int, ranks ...int) int32 {
+	v := int32(category)
+	for i := 0; i < 5; i++ {
+		v <<= 4
+		if i < len(ranks) {
+			v |= int32(ranks[i] + 1)
+		}
+	}
+	return v
+}
+
+// kickers returns the highest n ranks in set other than those used.
+func kickers(set uint16, n int, used ...int) []int {
+	for _, r := range used {
+		set &^= 1 << uint(r)
+	}
+	return topRanks(set, n)
+}
+
+// topRanks returns the highest n ranks in set from highest to lowest.
+func topRanks(set uint16, n int) []int {
+	ranks := make([]int, 0, n)
+	for r := len(Ranks) - 1; r >= 0 && len(ranks) < n; r-- {
+		if set&(1<<uint(r)) != 0 {
+			ranks = append(ranks, r)
+		}
+	}
+	return ranks
+}
+
+// straightHigh returns the rank of the highest card of the best straight in
+// set, or -1 if there is none. An ace may be low in a five high straight.
+func straightHigh(set uint16) int {
+	const five = 0x1F
+	for r := len(Ranks) - 1; r >= 4; r-- {
+		if set>>uint(r-4)&five == five {
+			return r
+		}
+	}
+	// A, 2, 3, 4, 5.
+	if set&0x100F == 0x100F {
+		return 3
+	}
+	return -1
+}
+
+func popCount(set uint16) int {
+	var n int
+	for ; set != 0; set &= set - 1 {
+		n++
+	}
+	return n
+}
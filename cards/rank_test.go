@@ -0,0 +1,43 @@
+package cards
+
+import (
+	"testing"
+)
+
+func checkCategory(expected int32, hand []string, test *testing.T) {
+	if cat := Rank(hand) >> 20; cat != expected {
+		test.Fatalf("The hand %v should be category %d, but was %d.\n", hand, expected, cat)
+	}
+}
+
+func TestRank(test *testing.T) {
+	checkCategory(StraightFlush, []string{"2c", "3c", "4c", "5c", "6c", "7c", "9c"}, test)
+	checkCategory(StraightFlush, []string{"Ac", "2c", "3c", "4c", "5c", "Kd", "9h"}, test)
+	checkCategory(FourOfAKind, []string{"Ac", "Ah", "Ad", "As", "7c", "8c", "9c"}, test)
+	checkCategory(FullHouse, []string{"2c", "2h", "2d", "Ts", "Tc", "Js", "Jh"}, test)
+	checkCategory(Flush, []string{"2c", "3c", "4c", "5c", "7c", "8c", "9c"}, test)
+	checkCategory(Straight, []string{"2s", "3h", "4c", "5d", "6c", "8c", "9c"}, test)
+	checkCategory(Straight, []string{"As", "2h", "3c", "4d", "5c", "Kc", "9c"}, test)
+	checkCategory(ThreeOfAKind, []string{"5c", "5h", "5s", "Td", "7c", "8c", "9c"}, test)
+	checkCategory(TwoPair, []string{"2c", "2d", "4h", "4s", "7c", "8c", "8d"}, test)
+	checkCategory(Pair, []string{"Ac", "Ad", "4s", "5h", "7d", "8c", "9c"}, test)
+	checkCategory(HighCard, []string{"Ac", "3d", "4s", "Tc", "7c", "5d", "9c"}, test)
+	checkCategory(Pair, []string{"3c", "3d"}, test)
+	checkCategory(HighCard, []string{"4c"}, test)
+
+	better := [][2][]string{
+		{{"Ac", "Ad", "4s", "5h", "7d", "8c", "9c"}, {"2c", "2d", "4s", "5h", "7d", "8c", "9c"}},
+		{{"Kc", "Kd", "Ks", "Qh", "Qd", "Jc", "Jh"}, {"Qc", "Qs", "Qd", "Kh", "Ks", "Jc", "Jh"}},
+		{{"2c", "3c", "4c", "5c", "6c"}, {"Ac", "2c", "3c", "4c", "5c"}},
+		{{"Ac", "Qd", "Td", "9h", "7s"}, {"Ac", "Qd", "Td", "9h", "6s"}},
+		{{"4d", "2c"}, {"3c", "2c"}},
+	}
+	for _, b := range better {
+		if Rank(b[0]) <= Rank(b[1]) {
+			test.Fatalf("%v did not beat %v.\n", b[0], b[1])
+		}
+	}
+	if Rank([]string{"Ac", "Kd", "7h"}) != Rank([]string{"Ad", "Kh", "7s"}) {
+		test.Fatalf("Hands that differ only in suits should tie.\n")
+	}
+}
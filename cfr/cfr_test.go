@@ -3,6 +3,10 @@ package cfr
 import (
 	"testing"
 	"fmt"
+	"math"
+
+	"poker/game"
+	"poker/game/diff"
 )
 
 func ExampleCFR() {
@@ -20,3 +24,92 @@ func ExampleNewStrategy() {
 func TestCalcNash(_ *testing.T) {
 	CalcNash(0)
 }
+
+// A kuhnNode holds the regrets and the summed strategy of an information set.
+type kuhnNode struct {
+	regret, total []float64
+}
+
+// kuhnCFR walks the tree of g, where each seat holds its card in hands, and
+// returns each seat's expected value. With update, the regrets and summed
+// strategies are updated with CFR for the chance of each seat reaching g;
+// otherwise the average strategies are played.
+func kuhnCFR(g *game.Game, nodes map[string]*kuhnNode, hands [][]string, history string,
+	reach []float64, update bool) []float64 {
+	if g.Actor < 0 || g.NumActive() < 2 {
+		return g.Payoffs(hands)
+	}
+	seat, legal := g.Actor, g.LegalActions()
+	key := hands[seat][0] + history
+	node, ok := nodes[key]
+	if !ok {
+		node = &kuhnNode{make([]float64, len(legal)), make([]float64, len(legal))}
+		nodes[key] = node
+	}
+	strat := NewStrategy(node.regret)
+	if !update {
+		strat = NewStrategy(node.total)
+	}
+	var sum float64
+	for _, p := range strat {
+		sum += p
+	}
+	for i := range strat {
+		if sum == 0 {
+			strat[i] = 1 / float64(len(strat))
+		} else {
+			strat[i] /= sum
+		}
+	}
+	ev := make([]float64, len(hands))
+	actEV := make([]float64, len(legal))
+	for i, a := range legal {
+		g.Apply(diff.Action(a))
+		r := append([]float64(nil), reach...)
+		r[seat] *= strat[i]
+		u := kuhnCFR(g, nodes, hands, history+string(a), r, update)
+		g.Undo()
+		actEV[i] = u[seat]
+		for j := range ev {
+			ev[j] += strat[i] * u[j]
+		}
+	}
+	if update {
+		for i, r := range CFR(actEV, strat, reach[1-seat]) {
+			node.regret[i] += r
+			node.total[i] += reach[seat] * strat[i]
+		}
+	}
+	return ev
+}
+
+func TestKuhn(test *testing.T) {
+	nodes := make(map[string]*kuhnNode)
+	// value returns the first player's value over every deal.
+	value := func(update bool) float64 {
+		var v float64
+		deck := []string{"2c", "3c", "4c"}
+		for _, c0 := range deck {
+			for _, c1 := range deck {
+				if c0 == c1 {
+					continue
+				}
+				g, err := game.NewGame("kuhn")
+				if err != nil {
+					test.Fatal(err)
+				}
+				g.Update(&diff.Players{Viewer: 0})
+				g.Update(diff.Cards(c0 + "|"))
+				hands := [][]string{{c0}, {c1}}
+				v += kuhnCFR(g, nodes, hands, "", []float64{1, 1}, update)[0] / 6
+			}
+		}
+		return v
+	}
+	for i := 0; i < 2000; i++ {
+		value(true)
+	}
+	if v := value(false); math.Abs(v+1.0/18) > 0.005 {
+		test.Fatalf("Expected the first player's value to be about -1/18, but got %g\n", v)
+	}
+}
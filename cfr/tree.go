@@ -46,7 +46,7 @@ type Terminal float64
 
 
 func newNode(g *game.Game) interface{} {
-	if g.Round == 4 || g.NumActive() < 2 {
+	if g.Round == g.NumRounds() || g.NumActive() < 2 {
 		return Terminal(g.Pot())
 	}
 	if g.Actor == -1 {
@@ -59,33 +59,34 @@ func newNode(g *game.Game) interface{} {
 }
 
 // addNodes recursively adds nodes to a game tree until all possible
-// playouts have been added.
+// playouts have been added. g is returned to its original state afterwards.
 func addNodes(node interface{}, g *game.Game) {
 	switch n := node.(type) {
 	case *Bucket:
-		g.Update(diff.Cards(""))
+		g.Apply(diff.Cards(""))
 		for i := range n.Classes {
 			n.Classes[i] = newNode(g)
 			addNodes(n.Classes[i], g)
 		}
+		g.Undo()
 	case *Player:
 		la := g.LegalActions()
 		s := 1 / float64(len(la))
 		for _, a := range la {
 			i := atoi[a]
 			n.Strat[i] = s
-			g1 := g.Copy()
-			g1.Update(diff.Action(itoa[i]))
-			n.Actions[i] = newNode(g1)
-			addNodes(n.Actions[i], g1)
+			g.Apply(diff.Action(itoa[i]))
+			n.Actions[i] = newNode(g)
+			addNodes(n.Actions[i], g)
+			g.Undo()
 		}
 	case *Opponent:
 		for _, a := range g.LegalActions() {
 			i := atoi[a]
-			g1 := g.Copy()
-			g1.Update(diff.Action(itoa[i]))
-			n.Actions[i] = newNode(g1)
-			addNodes(n.Actions[i], g1)
+			g.Apply(diff.Action(itoa[i]))
+			n.Actions[i] = newNode(g)
+			addNodes(n.Actions[i], g)
+			g.Undo()
 		}
 	case Terminal:
 	default:
@@ -0,0 +1,77 @@
+// Dealer runs a match between players that speak the ACPC protocol, like the
+// ACPC dealer. It listens on one port for each player, prints the ports, and
+// then waits for the players to connect in order. With -http, the players
+// join with the JSON play protocol over HTTP instead.
+//
+//	dealer [flags] name1 name2 ...
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"poker/game"
+	"poker/game/dealer"
+)
+
+func main() {
+	rules := flag.String("rules", "2p-l", "What rules to use: "+
+		strings.Join(game.RulesNames(), ", ")+", or an ACPC .game file.")
+	hands := flag.Int("hands", 1000, "How many hands to play.")
+	seed := flag.Int64("seed", 0, "The seed for shuffling the deck. 0 picks one from the time.")
+	port := flag.Int("port", 0, "The port for the first player. Each player after uses the next port. 0 picks any free ports.")
+	logFile := flag.String("log", "", "Where to write the log of the match.")
+	useHTTP := flag.Bool("http", false, "Serve the JSON play protocol over HTTP instead of the ACPC protocol.")
+	flag.Parse()
+	r, err := game.ChooseRules(*rules)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if flag.NArg() != r.NumPlayers() {
+		log.Fatalf("%s needs %d player names\n", r.Description(), r.NumPlayers())
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	d := &dealer.Dealer{Rules: r, Hands: *hands, Seed: *seed, Names: flag.Args()}
+	if *logFile != "" {
+		f, err := os.Create(*logFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		d.Log = f
+	}
+	listeners := make([]net.Listener, r.NumPlayers())
+	ports := make([]string, len(listeners))
+	for i := range listeners {
+		addr := ":0"
+		if *port != 0 {
+			addr = fmt.Sprintf(":%d", *port+i)
+		}
+		listeners[i], err = net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		_, ports[i], _ = net.SplitHostPort(listeners[i].Addr().String())
+	}
+	fmt.Println(strings.Join(ports, " "))
+	serve := d.Serve
+	if *useHTTP {
+		serve = d.PlayHTTP
+	}
+	results, err := serve(listeners)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	totals := results.Totals()
+	for i, name := range d.Names {
+		fmt.Printf("%s: %g (%.2f ± %.2f per hand)\n", name, totals[i],
+			results.Mean(i), results.StdErr(i))
+	}
+}
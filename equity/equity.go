@@ -131,9 +131,9 @@ func EvalHands(board []int32, hands ...[]int32) float64 {
 }
 
 // Choose k random items from p and put them in the first k positions of p.
-func sample(p []int32, k int, r int) []int32 {
+func sample(p []int32, k int, rng *rand.Rand) []int32 {
 	for i := 0; i < k; i++ {
-		j := RANDS[r].Intn(len(p) - i)
+		j := rng.Intn(len(p) - i)
 		p[i], p[i+j] = p[i+j], p[i]
 	}
 	return p[:k]
@@ -167,12 +167,12 @@ func handEquityE(hole, board, deck []int32) float64 {
 }
 
 // Monte-Carlo hand equity calculation.
-func handEquityMC(hole, board, deck []int32, trials, r int) float64 {
+func handEquityMC(hole, board, deck []int32, trials int, rng *rand.Rand) float64 {
 	var sum float64
 	bLen := len(board)
 	board = append(board, make([]int32, 5-bLen)...)
 	for i := 0; i < trials; i++ {
-		s := sample(deck, 7-bLen, r)
+		s := sample(deck, 7-bLen, rng)
 		copy(board[bLen:], s[2:])
 		sum += EvalHands(board, hole, s[:2])
 	}
@@ -181,18 +181,25 @@ func handEquityMC(hole, board, deck []int32, trials, r int) float64 {
 
 // Parallel Monte-Carlo hand equity calculation.
 func handEquityMCP(hole, board, deck []int32, trials, r int, c chan float64) {
-	c <- handEquityMC(hole, board, deck, trials, r)
+	c <- handEquityMC(hole, board, deck, trials, RANDS[r])
 }
 
 // HandEquity returns the equity of a player's hand based on the current
 // board.  trials is the number of Monte-Carlo simulations to do.  If trials
 // is 0, then exhaustive enumeration will be used instead.
 func HandEquity(sHand, sBoard []string, trials int) float64 {
+	return HandEquityRand(sHand, sBoard, trials, RANDS[0])
+}
+
+// HandEquityRand is HandEquity with the Monte-Carlo simulations drawn from
+// rng. The hand rank table is only read, so goroutines that each have their
+// own rng may call it at the same time.
+func HandEquityRand(sHand, sBoard []string, trials int, rng *rand.Rand) float64 {
 	hole, board, deck := handEquityInit(sHand, sBoard)
 	if trials == 0 {
 		return handEquityE(hole, board, deck)
 	}
-	return handEquityMC(hole, board, deck, trials, 0)
+	return handEquityMC(hole, board, deck, trials, rng)
 }
 
 // Parallel version of HandEquity.
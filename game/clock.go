@@ -0,0 +1,158 @@
+package game
+
+import (
+	"time"
+
+	"poker/game/diff"
+)
+
+// TimeLimits holds the time a player has to act. A limit of 0 means there is
+// none. A player who runs out of time checks or calls.
+type TimeLimits struct {
+	Action time.Duration // The most time for one action.
+	Hand   time.Duration // The most time for all of a player's actions in a hand.
+	Match  time.Duration // The most time for all of a player's actions in the match.
+}
+
+// A clock keeps track of the time a player has left, and stops waiting for
+// the player once it runs out. A player who is still playing after running
+// out of time is late: the match goes on without it, its turns are answered
+// with a check or call, the time it takes is charged to its time banks, and
+// it is told what it missed once it is back.
+type clock struct {
+	limits  TimeLimits
+	hand    time.Duration // The time left in the hand.
+	match   time.Duration // The time left in the match.
+	pending chan string   // Gets the action of a late player.
+	since   time.Time     // When a late player was last charged for its time.
+	missed  []func()      // The calls to make to a late player once it is back.
+}
+
+func newClock(limits TimeLimits) *clock {
+	return &clock{limits: limits, match: limits.Match}
+}
+
+// startHand fills the time bank for a new hand.
+func (this *clock) startHand() {
+	// Charge a late player for its time in the last hand first.
+	this.late()
+	this.hand = this.limits.Hand
+}
+
+// left returns the time the player has for its next action, or -1 if there is
+// no limit.
+func (this *clock) left() time.Duration {
+	left := time.Duration(-1)
+	for _, limit := range []struct{ set, left time.Duration }{
+		{this.limits.Action, this.limits.Action},
+		{this.limits.Hand, this.hand},
+		{this.limits.Match, this.match},
+	} {
+		if limit.set > 0 && (left < 0 || limit.left < left) {
+			left = limit.left
+		}
+	}
+	return left
+}
+
+// charge takes used from the time banks, down to no time left.
+func (this *clock) charge(used time.Duration) {
+	for _, bank := range []*time.Duration{&this.hand, &this.match} {
+		if *bank -= used; *bank < 0 {
+			*bank = 0
+		}
+	}
+}
+
+// late reports whether the player is still playing after running out of time,
+// charging it for the time it has taken. Once the player is back, the calls it
+// missed are made.
+func (this *clock) late() bool {
+	if this.pending == nil {
+		return false
+	}
+	now := time.Now()
+	this.charge(now.Sub(this.since))
+	this.since = now
+	select {
+	case <-this.pending:
+		this.back()
+		return false
+	default:
+		return true
+	}
+}
+
+// back makes the calls a late player missed, now that it has finished playing.
+func (this *clock) back() {
+	this.pending = nil
+	for len(this.missed) > 0 {
+		call := this.missed[0]
+		this.missed = this.missed[1:]
+		call()
+	}
+}
+
+// call calls f with g, or with a copy of g once the player is back if it is
+// late.
+func (this *clock) call(g *Game, f func(g *Game)) {
+	if this.late() {
+		c := g.Copy()
+		this.missed = append(this.missed, func() { f(c) })
+		return
+	}
+	f(g)
+}
+
+// play asks p to play with a deadline set in g, and returns "c" if p does not
+// answer in time. A player with no time left, or who is late, is not asked,
+// but observes g instead.
+func (this *clock) play(p Player, g *Game) string {
+	late := this.late()
+	left := this.left()
+	if left < 0 {
+		return p.Play(g)
+	}
+	if late || left == 0 {
+		this.call(g, p.Observe)
+		return "c"
+	}
+	// p plays a copy of g, so that the match can go on without it if it runs
+	// out of time.
+	c := g.Copy()
+	start := time.Now()
+	c.Deadline = start.Add(left)
+	pending := make(chan string, 1)
+	go func() {
+		pending <- p.Play(c)
+	}()
+	timer := time.NewTimer(left)
+	defer timer.Stop()
+	select {
+	case action := <-pending:
+		this.charge(time.Since(start))
+		return action
+	case <-timer.C:
+		this.pending, this.since = pending, start.Add(left)
+		this.charge(left)
+		return "c"
+	}
+}
+
+// finish waits for a late player to come back, and tells it what it missed.
+func (this *clock) finish() {
+	if this.pending != nil {
+		<-this.pending
+		this.back()
+	}
+}
+
+// PlayTimed plays a match as PlayWith does, but with time limits on p. While p
+// is playing, g.Deadline is the time it has to act by. When p runs out of
+// time, a check or call is sent in its place, and the match goes on without
+// waiting for p: its late action is ignored, it is told of the events it
+// missed once it is back, and the time it takes is charged to its hand and
+// match time. At the end of the match, PlayTimed waits for p to come back.
+func PlayTimed(engine diff.Engine, r *Rules, p Player, limits TimeLimits) error {
+	return playWith(engine, r, p, newClock(limits))
+}
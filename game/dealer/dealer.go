@@ -0,0 +1,416 @@
+// Package dealer implements the dealer's side of the ACPC protocol, so that
+// matches can be played without the ACPC dealer.
+//
+// The dealer deals the cards from a seeded deck, sends every player a
+// MATCHSTATE string after each action, checks the action sent back by the
+// player whose turn it is, and can write a log of the match in the same format
+// as the ACPC dealer. As in the ACPC dealer, the players move one seat each
+// hand and an invalid action is changed to the closest valid one, which is a
+// call if nothing else will do.
+package dealer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"poker/game"
+	"poker/game/diff"
+)
+
+type Dealer struct {
+	Rules *game.Rules
+	Hands int       // The number of hands to play.
+	Seed  int64     // The seed used to shuffle the deck.
+	Names []string  // The names of the players in the order they joined.
+	Log   io.Writer // Where to write a log of the match, if not nil.
+}
+
+// Serve accepts one player from each listener, in order, and then plays a
+// match between them.
+func (this *Dealer) Serve(listeners []net.Listener) (Results, error) {
+	conns := make([]io.ReadWriter, len(listeners))
+	for i, l := range listeners {
+		conn, err := l.Accept()
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		conns[i] = conn
+	}
+	return this.Play(conns)
+}
+
+// PlayLocal plays a match between players in this process, listed in the
+// order they joined. Each player gets the events of each hand straight from
+// the dealer through a diff.Local engine, without speaking the ACPC protocol.
+func (this *Dealer) PlayLocal(players []game.Player) (Results, error) {
+	locals := make([]*local, len(players))
+	seats := make([]seat, len(players))
+	var wg sync.WaitGroup
+	for i, p := range players {
+		locals[i] = &local{engine: diff.NewLocal(context.Background())}
+		seats[i] = locals[i]
+		wg.Add(1)
+		go func(p game.Player, engine diff.Engine) {
+			defer wg.Done()
+			game.PlayWith(engine, this.Rules, p)
+		}(p, locals[i].engine)
+	}
+	results, err := this.play(seats)
+	for _, l := range locals {
+		l.engine.End()
+	}
+	wg.Wait()
+	return results, err
+}
+
+// Play plays a match between players already connected by conns, listed in
+// the order they joined.
+func (this *Dealer) Play(conns []io.ReadWriter) (Results, error) {
+	if err := this.check(len(conns)); err != nil {
+		return nil, err
+	}
+	names := this.names()
+	seats := make([]seat, len(conns))
+	for i, conn := range conns {
+		p := newPlayer(conn)
+		line, err := p.readLine()
+		if err != nil {
+			return nil, fmt.Errorf("dealer: %s did not send a version: %v", names[i], err)
+		}
+		if !strings.HasPrefix(line, "VERSION:2.") {
+			return nil, fmt.Errorf("dealer: %s sent an unsupported version %q", names[i], line)
+		}
+		seats[i] = p
+	}
+	return this.play(seats)
+}
+
+// check returns an error if n players can't play the match.
+func (this *Dealer) check(n int) error {
+	if n != this.Rules.NumPlayers() {
+		return fmt.Errorf("dealer: %d players can't play %s", n, this.Rules.Description())
+	}
+	return nil
+}
+
+// names returns the names of the players in the order they joined.
+func (this *Dealer) names() []string {
+	names := make([]string, this.Rules.NumPlayers())
+	for i := range names {
+		if i < len(this.Names) && this.Names[i] != "" {
+			names[i] = this.Names[i]
+		} else {
+			names[i] = fmt.Sprintf("Player%d", i+1)
+		}
+	}
+	return names
+}
+
+// play plays a match between players, listed in the order they joined.
+func (this *Dealer) play(players []seat) (Results, error) {
+	if err := this.check(len(players)); err != nil {
+		return nil, err
+	}
+	n := len(players)
+	names := this.names()
+	var out *diff.ACPCLogWriter
+	if this.Log != nil {
+		out = diff.NewACPCLogWriter(this.Log)
+		out.Comment(fmt.Sprintf("%s, %d hands, seed %d", this.Rules.Description(), this.Hands, this.Seed))
+	}
+	rng := rand.New(rand.NewSource(this.Seed))
+	results := make(Results, 0, this.Hands)
+	for num := 0; num < this.Hands; num++ {
+		// The player who joined first sits in seat num % n.
+		seated := make([]seat, n)
+		seatNames := make([]string, n)
+		for i := range players {
+			seated[(i+num)%n] = players[i]
+			seatNames[(i+num)%n] = names[i]
+		}
+		h := newHand(this.Rules, num, rng)
+		if err := h.play(seated); err != nil {
+			return nil, err
+		}
+		payoffs := h.g.Payoffs(h.holes)
+		won := make([]float64, n)
+		for i := range players {
+			won[i] = payoffs[(i+num)%n]
+		}
+		results = append(results, won)
+		if out != nil {
+			if err := out.WriteHand(h.record(seatNames, payoffs)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if out != nil {
+		if err := out.WriteScore(); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// A seat is the dealer's connection to the player in it.
+type seat interface {
+	// update tells the player about h up to now, as seen from position.
+	update(h *hand, position int) error
+	// action waits for the player to respond to the latest update.
+	action() (string, error)
+}
+
+// A player is the dealer's connection to a player over the ACPC protocol.
+// Lines from the player are read as soon as they arrive, so that a player can
+// never be blocked waiting for the dealer to read.
+type player struct {
+	conn  io.Writer
+	lines chan string
+	err   error  // Why the connection ended, set before lines is closed.
+	sent  string // The last state sent to the player.
+}
+
+func newPlayer(conn io.ReadWriter) *player {
+	p := &player{conn: conn, lines: make(chan string, 1)}
+	go func() {
+		r := bufio.NewReader(conn)
+		defer close(p.lines)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				p.err = err
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if len(line) > 0 && line[0] != '#' && line[0] != ';' {
+				p.lines <- line
+			}
+		}
+	}()
+	return p
+}
+
+// readLine returns the next line from the player that is not a comment.
+func (this *player) readLine() (string, error) {
+	line, ok := <-this.lines
+	if !ok {
+		return "", this.err
+	}
+	return line, nil
+}
+
+func (this *player) update(h *hand, position int) error {
+	return this.send(h.state(position).String())
+}
+
+func (this *player) send(state string) error {
+	this.sent = state
+	_, err := fmt.Fprintf(this.conn, "%s\r\n", state)
+	return err
+}
+
+// action waits for the player to respond to the last state it was sent.
+// Responses to earlier states are ignored.
+func (this *player) action() (string, error) {
+	for {
+		line, err := this.readLine()
+		if err != nil {
+			return "", err
+		}
+		i := strings.LastIndex(line, ":")
+		if i >= 0 && line[:i] == this.sent {
+			return line[i+1:], nil
+		}
+	}
+}
+
+// A local is the dealer's connection to a player in the same process. The
+// events of each hand are sent to the player's diff.Local engine as they
+// happen, with the cards the player can't see left out.
+type local struct {
+	engine *diff.Local
+	hand   *hand // The hand being played.
+	sent   int   // The number of the hand's events sent to the player.
+}
+
+func (this *local) update(h *hand, position int) error {
+	if this.hand != h {
+		this.hand, this.sent = h, 0
+	}
+	for ; this.sent < len(h.events); this.sent++ {
+		event := h.events[this.sent]
+		switch this.sent {
+		case 0:
+			event = &diff.Players{Viewer: position}
+		case 1:
+			// The hole cards.
+			event = diff.Cards(joinHoles(h.state(position).Holes))
+		}
+		if !this.engine.Send(event) {
+			return diff.ErrClosed
+		}
+	}
+	if h.showdown() && !this.engine.Send(diff.Cards(joinHoles(h.state(position).Holes))) {
+		return diff.ErrClosed
+	}
+	return nil
+}
+
+func (this *local) action() (string, error) {
+	return this.engine.Action()
+}
+
+// A hand is the dealer's view of a single hand.
+type hand struct {
+	num     int
+	g       *game.Game
+	actions [][]diff.Action // The actions taken in each round.
+	holes   [][]string      // The hole cards of each seat.
+	board   [][]string      // The board cards dealt in each round.
+	events  []interface{}   // The events of the hand, with every seat's cards.
+	over    bool
+}
+
+func newHand(r *game.Rules, num int, rng *rand.Rand) *hand {
+	deck := r.Deck()
+	rng.Shuffle(len(deck), func(i, j int) {
+		deck[i], deck[j] = deck[j], deck[i]
+	})
+	h := &hand{num: num, g: game.NewGameRules(r)}
+	for i := 0; i < r.NumPlayers(); i++ {
+		h.holes = append(h.holes, deck[:r.NumHoleCards()])
+		deck = deck[r.NumHoleCards():]
+	}
+	for i := 0; i < r.NumRounds(); i++ {
+		h.board = append(h.board, deck[:r.NumBoardCards(i)])
+		deck = deck[r.NumBoardCards(i):]
+	}
+	return h
+}
+
+// play plays the hand between the players in seats.
+func (this *hand) play(seats []seat) error {
+	g := this.g
+	this.update(&diff.Players{Viewer: -1})
+	this.update(diff.Cards(joinHoles(this.holes)))
+	this.actions = [][]diff.Action{nil}
+	for {
+		// Deal until someone can act or the hand is over.
+		for g.Actor == -1 && g.NumActive() > 1 && g.Round < g.NumRounds()-1 {
+			this.update(diff.Cards(strings.Join(this.board[g.Round+1], "")))
+			this.actions = append(this.actions, nil)
+		}
+		if g.Actor == -1 {
+			break
+		}
+		if err := this.send(seats); err != nil {
+			return err
+		}
+		p := seats[g.Actor]
+		a, err := p.action()
+		if err != nil {
+			return fmt.Errorf("dealer: lost seat %d: %v", g.Actor, err)
+		}
+		valid := this.fix(a)
+		if len(a) == 0 || valid[0] != a[0] {
+			log.Printf("dealer: changed invalid action %q to %q in hand %d\n", a, valid, this.num)
+		}
+		this.actions[len(this.actions)-1] = append(this.actions[len(this.actions)-1], diff.Action(valid))
+		this.update(diff.Action(valid))
+	}
+	this.over = true
+	return this.send(seats)
+}
+
+// update updates the game with event and records it.
+func (this *hand) update(event interface{}) {
+	this.g.Update(event)
+	this.events = append(this.events, event)
+}
+
+// send tells the players in seats about the hand up to now.
+func (this *hand) send(seats []seat) error {
+	for i, p := range seats {
+		if err := p.update(this, i); err != nil {
+			return fmt.Errorf("dealer: lost seat %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// fix returns the valid action closest to a.
+func (this *hand) fix(a string) string {
+	g := this.g
+	legal := g.LegalActions()
+	switch {
+	case a == "f" && strings.Contains(legal, "f"):
+		return a
+	case len(a) > 0 && a[0] == 'r' && strings.Contains(legal, "r"):
+		if g.Limit() {
+			return "r"
+		}
+		to, err := strconv.Atoi(a[1:])
+		if err != nil || float64(to) < g.MinRaise() {
+			to = int(g.MinRaise())
+		}
+		if float64(to) > g.MaxRaise() {
+			to = int(g.MaxRaise())
+		}
+		return fmt.Sprintf("r%d", to)
+	}
+	return "c"
+}
+
+// record returns the record of the finished hand for a log.
+func (this *hand) record(names []string, payoffs []float64) *diff.Hand {
+	return &diff.Hand{
+		Num:     this.num,
+		Players: names,
+		Actions: this.actions,
+		Holes:   this.holes,
+		Board:   this.board[:len(this.actions)],
+		Results: payoffs,
+	}
+}
+
+// state returns the state seen from seat, or all of the cards if seat is -1.
+// Players see the hole cards of everyone who has not folded at the showdown.
+func (this *hand) state(seat int) *diff.MatchState {
+	showdown := this.showdown()
+	holes := make([][]string, len(this.holes))
+	for i, h := range this.holes {
+		if seat == -1 || i == seat || (showdown && this.g.Actions[i] != 'f') {
+			holes[i] = h
+		}
+	}
+	return &diff.MatchState{
+		Position: seat,
+		HandNum:  this.num,
+		Actions:  this.actions,
+		Holes:    holes,
+		Board:    this.board[:len(this.actions)],
+	}
+}
+
+// showdown returns whether the hand is over with more than one player left.
+func (this *hand) showdown() bool {
+	return this.over && this.g.NumActive() > 1
+}
+
+// joinHoles joins the hole cards of each seat as in a Cards event.
+func joinHoles(holes [][]string) string {
+	seats := make([]string, len(holes))
+	for i, h := range holes {
+		seats[i] = strings.Join(h, "")
+	}
+	return strings.Join(seats, "|")
+}
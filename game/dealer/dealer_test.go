@@ -0,0 +1,242 @@
+package dealer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"poker/game"
+	"poker/game/diff"
+)
+
+// caller always checks or calls.
+type caller struct{}
+
+func (_ caller) Observe(_ *game.Game) {}
+
+func (_ caller) Play(_ *game.Game) string {
+	return "c"
+}
+
+// raiser raises whenever it can.
+type raiser struct{}
+
+func (_ raiser) Observe(_ *game.Game) {}
+
+func (_ raiser) Play(g *game.Game) string {
+	if strings.Contains(g.LegalActions(), "r") {
+		return "r"
+	}
+	return "c"
+}
+
+func TestServe(test *testing.T) {
+	for _, rules := range []string{"kuhn", "leduc", "2p-l", "3p-l", "2p-nl"} {
+		r, err := game.ChooseRules(rules)
+		if err != nil {
+			test.Fatal(err)
+		}
+		listeners := make([]net.Listener, r.NumPlayers())
+		for i := range listeners {
+			listeners[i], err = net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				test.Fatal(err)
+			}
+			defer listeners[i].Close()
+			host, port, _ := net.SplitHostPort(listeners[i].Addr().String())
+			var p game.Player = caller{}
+			if i == 0 {
+				p = raiser{}
+			}
+			go game.Play(rules, p, host, port)
+		}
+		buf := new(bytes.Buffer)
+		d := &Dealer{Rules: r, Hands: 20, Seed: 1, Log: buf}
+		results, err := d.Serve(listeners)
+		if err != nil {
+			test.Fatal(err)
+		}
+		totals := results.Totals()
+		var sum float64
+		for _, t := range totals {
+			sum += t
+		}
+		if math.Abs(sum) > 1e-9 {
+			test.Fatalf("%s: the winnings %v do not add up to 0\n", rules, totals)
+		}
+		log := diff.NewACPCLog(strings.NewReader(buf.String()))
+		var n int
+		var first float64
+		for {
+			h, err := log.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				test.Fatal(err)
+			}
+			first += h.Results[h.Seat("Player1")]
+			n++
+		}
+		if n != d.Hands || first != totals[0] {
+			test.Fatalf("%s: logged %d hands instead of %d:\n%s", rules, n, d.Hands, buf)
+		}
+	}
+}
+
+func TestPlayLocal(test *testing.T) {
+	for _, rules := range []string{"kuhn", "3p-l", "2p-nl"} {
+		r, err := game.ChooseRules(rules)
+		if err != nil {
+			test.Fatal(err)
+		}
+		players := []game.Player{raiser{}}
+		for len(players) < r.NumPlayers() {
+			players = append(players, caller{})
+		}
+		d := &Dealer{Rules: r, Hands: 100, Seed: 2}
+		results, err := d.PlayLocal(players)
+		if err != nil {
+			test.Fatal(err)
+		}
+		totals := results.Totals()
+		var sum float64
+		for _, t := range totals {
+			sum += t
+		}
+		if math.Abs(sum) > 1e-9 {
+			test.Fatalf("%s: the winnings %v do not add up to 0\n", rules, totals)
+		}
+	}
+}
+
+// playPipe plays a match like PlayLocal, but with each player connected to
+// the dealer by a net.Pipe over the ACPC protocol.
+func playPipe(d *Dealer, players []game.Player) (Results, error) {
+	conns := make([]io.ReadWriter, len(players))
+	errs := make(chan error, len(players))
+	for i, p := range players {
+		server, client := net.Pipe()
+		conns[i] = server
+		go func(p game.Player, client net.Conn) {
+			engine, err := diff.NewACPC(context.Background(), client, diff.ACPCOptions{})
+			if err != nil {
+				errs <- err
+				return
+			}
+			errs <- game.PlayWith(engine, d.Rules, p)
+		}(p, client)
+	}
+	results, err := d.Play(conns)
+	// Closing the dealer's end of each pipe ends the players' event streams.
+	for _, c := range conns {
+		c.(net.Conn).Close()
+	}
+	for range players {
+		if e := <-errs; err == nil {
+			err = e
+		}
+	}
+	return results, err
+}
+
+func TestPlayPipe(test *testing.T) {
+	for _, rules := range []string{"kuhn", "3p-l", "2p-nl"} {
+		r, err := game.ChooseRules(rules)
+		if err != nil {
+			test.Fatal(err)
+		}
+		players := []game.Player{raiser{}, caller{}, caller{}}[:r.NumPlayers()]
+		d := &Dealer{Rules: r, Hands: 50, Seed: 5}
+		results, err := playPipe(d, players)
+		if err != nil {
+			test.Fatal(err)
+		}
+		local, err := d.PlayLocal(players)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if !reflect.DeepEqual(results, local) {
+			test.Fatalf("%s: won %v over the ACPC protocol but %v locally\n", rules, results.Totals(), local.Totals())
+		}
+	}
+}
+
+func TestDuplicate(test *testing.T) {
+	r, err := game.ChooseRules("2p-l")
+	if err != nil {
+		test.Fatal(err)
+	}
+	// Two players who play the same way break even on every deal.
+	d := &Dealer{Rules: r, Hands: 100, Seed: 3}
+	results, err := d.Duplicate([]game.Player{caller{}, caller{}})
+	if err != nil {
+		test.Fatal(err)
+	}
+	if len(results) != d.Hands || results.Mean(0) != 0 || results.StdErr(0) != 0 {
+		test.Fatalf("Expected equal players to break even, but won %.2f ± %.2f\n",
+			results.Mean(0), results.StdErr(0))
+	}
+	results, err = d.Duplicate([]game.Player{raiser{}, caller{}})
+	if err != nil {
+		test.Fatal(err)
+	}
+	single, err := d.PlayLocal([]game.Player{raiser{}, caller{}})
+	if err != nil {
+		test.Fatal(err)
+	}
+	if results.StdErr(0) >= single.StdErr(0) {
+		test.Fatalf("Expected a duplicate match to have less variance, but %.2f >= %.2f\n",
+			results.StdErr(0), single.StdErr(0))
+	}
+}
+
+func TestPlayHTTP(test *testing.T) {
+	for _, rules := range []string{"kuhn", "3p-l", "2p-nl"} {
+		r, err := game.ChooseRules(rules)
+		if err != nil {
+			test.Fatal(err)
+		}
+		listeners := make([]net.Listener, r.NumPlayers())
+		errs := make(chan error, len(listeners))
+		for i := range listeners {
+			listeners[i], err = net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				test.Fatal(err)
+			}
+			engine, err := diff.DialHTTP(context.Background(),
+				"http://"+listeners[i].Addr().String(), diff.HTTPOptions{})
+			if err != nil {
+				test.Fatal(err)
+			}
+			var p game.Player = caller{}
+			if i == 0 {
+				p = raiser{}
+			}
+			go func() {
+				errs <- game.PlayWith(engine, r, p)
+			}()
+		}
+		d := &Dealer{Rules: r, Hands: 20, Seed: 4}
+		results, err := d.PlayHTTP(listeners)
+		if err != nil {
+			test.Fatal(err)
+		}
+		for range listeners {
+			if err := <-errs; err != nil {
+				test.Fatalf("%s: %v", rules, err)
+			}
+		}
+		local, err := d.PlayLocal([]game.Player{raiser{}, caller{}, caller{}}[:r.NumPlayers()])
+		if err != nil {
+			test.Fatal(err)
+		}
+		if !reflect.DeepEqual(results, local) {
+			test.Fatalf("%s: won %v over HTTP but %v locally\n", rules, results.Totals(), local.Totals())
+		}
+	}
+}
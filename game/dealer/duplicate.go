@@ -0,0 +1,50 @@
+package dealer
+
+import (
+	"fmt"
+
+	"poker/game"
+)
+
+// Duplicate plays a duplicate match between players in this process to
+// reduce the effect of luck on the results. The match is played once for each
+// rotation of the players with the same seed, so that every player is dealt
+// the cards of every seat. The result of each hand is each player's average
+// over the rotations, so the cards dealt cancel out when the players are
+// equally good. The same players are used for every rotation, so players that
+// learn as they play will see each deal more than once.
+func (this *Dealer) Duplicate(players []game.Player) (Results, error) {
+	n := len(players)
+	var results Results
+	for k := 0; k < n; k++ {
+		rotated := make([]game.Player, n)
+		names := make([]string, n)
+		for i := range rotated {
+			p := (i + k) % n
+			rotated[i] = players[p]
+			if p < len(this.Names) {
+				names[i] = this.Names[p]
+			} else {
+				names[i] = fmt.Sprintf("Player%d", p+1)
+			}
+		}
+		d := *this
+		d.Names = names
+		r, err := d.PlayLocal(rotated)
+		if err != nil {
+			return nil, err
+		}
+		if results == nil {
+			results = make(Results, len(r))
+			for h := range results {
+				results[h] = make([]float64, n)
+			}
+		}
+		for h, won := range r {
+			for i, chips := range won {
+				results[h][(i+k)%n] += chips / float64(n)
+			}
+		}
+	}
+	return results, nil
+}
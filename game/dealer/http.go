@@ -0,0 +1,249 @@
+package dealer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"poker/game/diff"
+)
+
+// An HTTPSeat is a seat at the table for a player who speaks the JSON play
+// protocol described in package diff. It serves the player as an
+// http.Handler, and is the player's connection for Play: it reads the
+// player's actions as ACPC responses and writes the dealer's states to the
+// player.
+type HTTPSeat struct {
+	// How long a poll waits for a new state before it is answered without
+	// one. If 0, it waits 30 seconds.
+	PollTimeout time.Duration
+
+	mu       sync.Mutex
+	states   []*diff.MatchState // Every state sent to the player.
+	partial  []byte             // The start of a state not written in full yet.
+	changed  chan struct{}      // Closed when there is a new state or the match ends.
+	joined   bool
+	over     bool
+	lines    chan string   // Lines from the player for the dealer.
+	pending  []byte        // The rest of the line being read.
+	closed   chan struct{} // Closed when the match ends.
+	finished chan struct{} // Closed once the player is told that the match is over.
+	close    sync.Once
+	finish   sync.Once
+}
+
+// NewHTTPSeat returns an empty seat.
+func NewHTTPSeat() *HTTPSeat {
+	return &HTTPSeat{
+		changed:  make(chan struct{}),
+		lines:    make(chan string, 1),
+		closed:   make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+}
+
+// Read reads the lines sent by the player: the version when the player joins,
+// and its actions. It returns io.EOF once the seat is closed.
+func (this *HTTPSeat) Read(p []byte) (int, error) {
+	if len(this.pending) == 0 {
+		select {
+		case line := <-this.lines:
+			this.pending = []byte(line)
+		case <-this.closed:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, this.pending)
+	this.pending = this.pending[n:]
+	return n, nil
+}
+
+// Write takes the MATCHSTATE lines from the dealer and gives them to the
+// player.
+func (this *HTTPSeat) Write(p []byte) (int, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.over {
+		return 0, io.ErrClosedPipe
+	}
+	this.partial = append(this.partial, p...)
+	for {
+		i := strings.IndexByte(string(this.partial), '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(this.partial[:i]), "\r")
+		this.partial = this.partial[i+1:]
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		state, err := diff.ParseMatchState(line)
+		if err != nil {
+			return 0, err
+		}
+		this.states = append(this.states, state)
+		close(this.changed)
+		this.changed = make(chan struct{})
+	}
+	return len(p), nil
+}
+
+// Close ends the match. The player is told that it is over after it has been
+// given every state.
+func (this *HTTPSeat) Close() error {
+	this.close.Do(func() {
+		this.mu.Lock()
+		this.over = true
+		close(this.changed)
+		this.mu.Unlock()
+		close(this.closed)
+	})
+	return nil
+}
+
+// Finished returns a channel that is closed once the player has been told
+// that the match is over.
+func (this *HTTPSeat) Finished() <-chan struct{} {
+	return this.finished
+}
+
+// ServeHTTP serves GET /state and POST /action.
+func (this *HTTPSeat) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/state":
+		if r.Method != "GET" {
+			http.Error(w, "only GET is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		this.serveState(w, r)
+	case "/action":
+		if r.Method != "POST" {
+			http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		this.serveAction(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveState answers a poll for the state after the one numbered by the
+// after parameter.
+func (this *HTTPSeat) serveState(w http.ResponseWriter, r *http.Request) {
+	after := 0
+	if s := r.URL.Query().Get("after"); s != "" {
+		var err error
+		if after, err = strconv.Atoi(s); err != nil || after < 0 {
+			http.Error(w, fmt.Sprintf("invalid after %q", s), http.StatusBadRequest)
+			return
+		}
+	}
+	this.mu.Lock()
+	if !this.joined {
+		this.joined = true
+		this.lines <- diff.ACPCversion
+	}
+	this.mu.Unlock()
+	timeout := this.PollTimeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		this.mu.Lock()
+		reply := diff.HTTPState{Seq: after}
+		if after > len(this.states) {
+			this.mu.Unlock()
+			http.Error(w, fmt.Sprintf("there is no state %d", after), http.StatusBadRequest)
+			return
+		} else if after < len(this.states) {
+			reply.Seq, reply.State = after+1, this.states[after]
+		}
+		reply.Over = this.over && reply.Seq == len(this.states)
+		changed := this.changed
+		this.mu.Unlock()
+		if reply.State != nil || reply.Over {
+			writeJSON(w, &reply)
+			if reply.Over {
+				this.finish.Do(func() { close(this.finished) })
+			}
+			return
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			writeJSON(w, &reply)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// serveAction passes an action on to the dealer as the response to the state
+// it is for.
+func (this *HTTPSeat) serveAction(w http.ResponseWriter, r *http.Request) {
+	var a diff.HTTPAction
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	this.mu.Lock()
+	if a.Seq < 1 || a.Seq > len(this.states) {
+		this.mu.Unlock()
+		http.Error(w, fmt.Sprintf("there is no state %d", a.Seq), http.StatusBadRequest)
+		return
+	}
+	line := fmt.Sprintf("%s:%s\n", this.states[a.Seq-1], a.Action)
+	this.mu.Unlock()
+	select {
+	case this.lines <- line:
+		w.WriteHeader(http.StatusNoContent)
+	case <-this.closed:
+		http.Error(w, "the match is over", http.StatusGone)
+	case <-r.Context().Done():
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// PlayHTTP serves a seat with the JSON play protocol on each listener, in
+// order, and plays a match between the players once they have all joined.
+// The servers are shut down once every player has been told that the match is
+// over, or after 30 seconds.
+func (this *Dealer) PlayHTTP(listeners []net.Listener) (Results, error) {
+	seats := make([]*HTTPSeat, len(listeners))
+	conns := make([]io.ReadWriter, len(listeners))
+	servers := make([]*http.Server, len(listeners))
+	for i, l := range listeners {
+		seats[i] = NewHTTPSeat()
+		conns[i] = seats[i]
+		servers[i] = &http.Server{Handler: seats[i]}
+		go servers[i].Serve(l)
+	}
+	results, err := this.Play(conns)
+	for i, seat := range seats {
+		seat.Close()
+		timer := time.NewTimer(30 * time.Second)
+		select {
+		case <-seat.Finished():
+		case <-timer.C:
+		}
+		timer.Stop()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		servers[i].Shutdown(ctx)
+		cancel()
+	}
+	return results, err
+}
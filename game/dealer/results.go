@@ -0,0 +1,47 @@
+package dealer
+
+import "math"
+
+// Results holds the chips won by each player in each hand of a match, with
+// the players in the order they joined.
+type Results [][]float64
+
+// Totals returns the chips won by each player over the whole match.
+func (this Results) Totals() []float64 {
+	var totals []float64
+	for _, won := range this {
+		if totals == nil {
+			totals = make([]float64, len(won))
+		}
+		for i, chips := range won {
+			totals[i] += chips
+		}
+	}
+	return totals
+}
+
+// Mean returns the average chips won per hand by player.
+func (this Results) Mean(player int) float64 {
+	if len(this) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, won := range this {
+		sum += won[player]
+	}
+	return sum / float64(len(this))
+}
+
+// StdErr returns the standard error of the mean chips won per hand by player.
+func (this Results) StdErr(player int) float64 {
+	if len(this) < 2 {
+		return 0
+	}
+	mean := this.Mean(player)
+	var ss float64
+	for _, won := range this {
+		d := won[player] - mean
+		ss += d * d
+	}
+	return math.Sqrt(ss / float64(len(this)-1) / float64(len(this)))
+}
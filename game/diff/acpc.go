@@ -2,11 +2,12 @@ package diff
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"net"
-	"strconv"
 	"strings"
+	"time"
 )
 
 const ACPCversion = "VERSION:2.0.0\r\n"
@@ -20,80 +21,109 @@ const (
 	_CARDS
 )
 
-func NewACPC(addr string) (chan interface{}, chan string, error) {
-	conn, err := net.Dial("tcp", addr)
+// ACPCOptions holds the time limits for talking to an ACPC dealer. A limit of
+// 0 means there is none.
+type ACPCOptions struct {
+	DialTimeout time.Duration // How long to wait to connect.
+	// How long to wait for the next message from the dealer. The dealer may be
+	// waiting on the other players, so this should be generous.
+	ReadTimeout time.Duration
+}
+
+// ACPC is a diff engine for playing with an ACPC dealer. Events from the dealer
+// are sent on Events, which is closed when the match is over, the connection
+// is lost, Close is called, or the context is done. Actions sent on Actions are
+// sent to the dealer as the response to the latest state.
+type ACPC struct {
+	channels
+	conn    io.ReadWriteCloser
+	opts    ACPCOptions
+	gstring string // The latest state from the dealer.
+}
+
+// DialACPC connects to an ACPC dealer at addr.
+func DialACPC(ctx context.Context, addr string, opts ACPCOptions) (*ACPC, error) {
+	d := net.Dialer{Timeout: opts.DialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
+	return NewACPC(ctx, conn, opts)
+}
+
+// NewACPC speaks the ACPC protocol with a dealer over conn, which may be any
+// connection, such as one end of a net.Pipe. conn is closed when the match
+// ends. Read timeouts only work if conn has a SetReadDeadline method.
+func NewACPC(ctx context.Context, conn io.ReadWriteCloser, opts ACPCOptions) (*ACPC, error) {
 	// Tell the dealer we are ready to start playing.
-	conn.Write([]byte(ACPCversion))
-	var gstring string
-	in := make(chan string)
-	go func() {
-		for {
-			a := <-in
-			fmt.Fprintf(conn, "%s:%s\r\n", gstring, a)
-		}
-	}()
-	ch := make(chan interface{}, 3)
-	go func() {
-		var offsets [5]int
-		var state []string
-		var handNum string
-		var position, round int
-		bufin := bufio.NewReader(conn)
-		defer conn.Close()
-		defer close(ch)
-		for {
-			line, err := bufin.ReadString('\n')
-			if err == io.EOF {
-				fmt.Println("Received EOF, shutting down.")
-				return
-			} else if err != nil {
-				fmt.Println("Error during game update:", err)
+	if _, err := io.WriteString(conn, ACPCversion); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	this := &ACPC{conn: conn, opts: opts}
+	this.start(ctx, conn.Close)
+	go this.send()
+	go this.receive()
+	return this, nil
+}
+
+func (this *ACPC) send() {
+	for {
+		select {
+		case a := <-this.actions:
+			this.mu.Lock()
+			gstring := this.gstring
+			this.mu.Unlock()
+			if _, err := fmt.Fprintf(this.conn, "%s:%s\r\n", gstring, a); err != nil {
+				this.fail(err)
 				return
-			// ";" and "#" are comment lines.
-			} else if len(line) < 1 || line[0] == ';' || line[0] == '#' {
-				continue
 			}
-			// Only update the gstring if there game has advanced.
-			gstring = strings.TrimRight(line, "\r\n")
-			state = strings.Split(gstring, ":")
-			// New hand.
-			if handNum != state[_HAND_NUM] {
-				handNum = state[_HAND_NUM]
-				round = 0
-				position, err = strconv.Atoi(state[_POSITION])
-				if err != nil {
-					fmt.Printf("Recieved invalid position value %s\n", state[_POSITION])
-					return
-				}
-				ch <- &Players{Viewer: position}
-				// New hole cards.
-				ch <- Cards(strings.Trim(state[_CARDS], "/|"))
-				for i := range offsets {
-					offsets[i] = 0
-				}
-			} else {
-				// New action.
-				ch <- Action(state[_BETS][offsets[_BETS]])
-				// New round.
-				if len(state[_CARDS][offsets[_CARDS]:]) > 0 {
-					round++
-					// New board cards.
-					if round != 4 {
-						ch <- Cards(state[_CARDS][offsets[_CARDS]:])
-					// Hole cards revealed.
-					} else {
-						ch <- Cards(strings.SplitN(state[_CARDS], "/", 2)[0])
-					}
-				}
-			}
-			// Update offsets.
-			for i := range state {
-				offsets[i] = len(state[i])
+		case <-this.done:
+			return
+		}
+	}
+}
+
+func (this *ACPC) receive() {
+	defer this.finish()
+	var prev *MatchState
+	bufin := bufio.NewReader(this.conn)
+	deadline, _ := this.conn.(interface {
+		SetReadDeadline(time.Time) error
+	})
+	for {
+		if deadline != nil && this.opts.ReadTimeout > 0 {
+			deadline.SetReadDeadline(time.Now().Add(this.opts.ReadTimeout))
+		}
+		line, err := bufin.ReadString('\n')
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			this.fail(err)
+			return
+		// ";" and "#" are comment lines.
+		} else if len(line) < 1 || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		gstring := strings.TrimRight(line, "\r\n")
+		state, err := ParseMatchState(gstring)
+		if err != nil {
+			this.fail(err)
+			return
+		}
+		events, err := state.Events(prev)
+		if err != nil {
+			this.fail(err)
+			return
+		}
+		this.mu.Lock()
+		this.gstring = gstring
+		this.mu.Unlock()
+		for _, e := range events {
+			if !this.emit(e) {
+				return
 			}
 		}
-	}()
-	return ch, in, nil
+		prev = state
+	}
 }
@@ -0,0 +1,93 @@
+package diff
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// dealer reads the version sent by a new client and returns a reader for the
+// client's responses.
+func dealer(test *testing.T, conn net.Conn) *bufio.Reader {
+	r := bufio.NewReader(conn)
+	if line, err := r.ReadString('\n'); err != nil || line != ACPCversion {
+		test.Fatalf("Expected %q, but got %q %v\n", ACPCversion, line, err)
+	}
+	return r
+}
+
+func TestACPC(test *testing.T) {
+	server, client := net.Pipe()
+	go func() {
+		engine, err := NewACPC(context.Background(), client, ACPCOptions{})
+		if err != nil {
+			panic(err)
+		}
+		for e := range engine.Events() {
+			if a, ok := e.(Action); ok && a == "r" {
+				engine.Actions() <- "c"
+			}
+		}
+	}()
+	r := dealer(test, server)
+	fmt.Fprintf(server, "MATCHSTATE:1:0::|9hQd\r\n")
+	fmt.Fprintf(server, "MATCHSTATE:1:0:r:|9hQd\r\n")
+	if line, err := r.ReadString('\n'); err != nil || line != "MATCHSTATE:1:0:r:|9hQd:c\r\n" {
+		test.Fatalf("Expected a call, but got %q %v\n", line, err)
+	}
+	server.Close()
+}
+
+func TestACPCErrors(test *testing.T) {
+	server, client := net.Pipe()
+	done := make(chan error)
+	go func() {
+		engine, err := NewACPC(context.Background(), client, ACPCOptions{})
+		if err != nil {
+			panic(err)
+		}
+		for range engine.Events() {
+		}
+		done <- <-engine.Errors()
+	}()
+	dealer(test, server)
+	fmt.Fprintf(server, "GARBAGE\r\n")
+	if err := <-done; err == nil {
+		test.Fatalf("Expected an error after an invalid state\n")
+	}
+
+	server, client = net.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		engine, err := NewACPC(ctx, client, ACPCOptions{})
+		if err != nil {
+			panic(err)
+		}
+		for range engine.Events() {
+		}
+		done <- <-engine.Errors()
+	}()
+	dealer(test, server)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		test.Fatalf("Expected %v, but got %v\n", context.Canceled, err)
+	}
+
+	server, client = net.Pipe()
+	go func() {
+		engine, err := NewACPC(context.Background(), client, ACPCOptions{ReadTimeout: time.Millisecond})
+		if err != nil {
+			panic(err)
+		}
+		for range engine.Events() {
+		}
+		done <- <-engine.Errors()
+	}()
+	dealer(test, server)
+	if err := <-done; err == nil {
+		test.Fatalf("Expected a timeout\n")
+	}
+}
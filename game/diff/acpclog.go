@@ -1,72 +1,288 @@
 package diff
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"math"
+	"strconv"
 	"strings"
-	"io/ioutil"
 )
 
-// The structure of record string is
-// STATE:handNum:actions:cards:result:playerPositions
-type record struct {
-	actions []string // Actions split by round.
-	holes   []string // Hole cards split by player.
-	board   []string // Board cards split by round.
-	players []string // Players indexed by position.
+// A Hand is the record of one finished hand of a match. Seats are numbered
+// from 0 in their order after the button, as in the ACPC protocol, so that
+// the button is the last seat.
+type Hand struct {
+	Num     int        // The number of the hand in the match.
+	Players []string   // The name of the player in each seat.
+	Blinds  []float64  // The blind posted by each seat, if known.
+	Stacks  []float64  // The chips each seat started with, if known.
+	Actions [][]Action // The actions taken in each round.
+	Holes   [][]string // The hole cards of each seat.
+	Board   [][]string // The board cards dealt in each round.
+	Results []float64  // The chips won or lost by each seat.
 }
 
-func newRecord(line string) *record {
-	s := strings.Split(line, ":")
-	cards := strings.SplitN(s[3], "/", 2)
-	return &record{
-		actions: strings.Split(s[2], "/"),
-		holes:   strings.Split(cards[0], "|"),
-		board:   strings.Split(cards[1], "/"),
-		players: strings.Split(s[5], "|")}
+// A HandReader reads hands from a hand history one at a time. Next returns
+// io.EOF when there are no more hands.
+type HandReader interface {
+	Next() (*Hand, error)
 }
 
-type ACPCLog struct {
-	hands    []string // All of the hands in the log file.
-	action   string   // The most recent action.
-	cards    string   // The most recently revealed cards.
-	round    int      // The current round.
-	// The players at the table ordered by their position relative to the
-	// dealer button.
-	players  []string
+// HistoryFormats lists the formats of hand histories NewHandReader can read.
+const HistoryFormats = "acpc, pokerstars, or json"
+
+// NewHandReader returns a reader of hands from a history in a format named in
+// HistoryFormats.
+func NewHandReader(format string, r io.Reader) (HandReader, error) {
+	switch format {
+	case "acpc":
+		return NewACPCLog(r), nil
+	case "pokerstars":
+		return NewPokerStarsLog(r), nil
+	case "json":
+		return NewJSONLog(r), nil
+	}
+	return nil, fmt.Errorf("diff: the format %s is not one of %s", format, HistoryFormats)
 }
 
-func (this *ACPCLog) String() string {
-	return fmt.Sprintln(this.round, this.action, this.cards)
+// A HandWriter writes hands to a hand history one at a time.
+type HandWriter interface {
+	WriteHand(h *Hand) error
 }
 
-func NewACPCLog(file string) (*ACPCLog, error) {
-	log, err := ioutil.ReadFile(file)
-	if err != nil {
-		return nil, err
+// ParseHand parses a hand from an ACPC log record, such as
+//
+//	STATE:0:rrrc/rc/rc/cc:5d5c|9hQd/8dAs8s/4h/6d:70|-70:Alice|Hobbes
+//
+// which gives the hand number, the actions, the cards, the results, and the
+// players in each seat.
+func ParseHand(s string) (*Hand, error) {
+	fields := strings.Split(s, ":")
+	if len(fields) != 6 || fields[0] != "STATE" {
+		return nil, fmt.Errorf("diff: invalid hand %q", s)
+	}
+	h := new(Hand)
+	var err error
+	if h.Num, err = parseCount(fields[1]); err != nil {
+		return nil, fmt.Errorf("diff: invalid hand number in %q", s)
+	}
+	if h.Actions, err = parseBetting(fields[2]); err != nil {
+		return nil, fmt.Errorf("diff: %v in %q", err, s)
+	}
+	if h.Holes, h.Board, err = parseCards(fields[3]); err != nil {
+		return nil, fmt.Errorf("diff: %v in %q", err, s)
+	}
+	for _, r := range strings.Split(fields[4], "|") {
+		f, err := strconv.ParseFloat(r, 64)
+		if err != nil {
+			return nil, fmt.Errorf("diff: invalid result %q in %q", r, s)
+		}
+		h.Results = append(h.Results, f)
+	}
+	h.Players = strings.Split(fields[5], "|")
+	if len(h.Results) != len(h.Holes) || len(h.Players) != len(h.Holes) {
+		return nil, fmt.Errorf("diff: the number of seats differs in %q", s)
+	}
+	if len(h.Board) != len(h.Actions) {
+		return nil, fmt.Errorf("diff: the cards and actions are for different rounds in %q", s)
+	}
+	return h, nil
+}
+
+// String formats the hand as an ACPC log record, which does not include the
+// blinds or stacks.
+func (this *Hand) String() string {
+	return fmt.Sprintf("STATE:%d:%s:%s:%s:%s", this.Num, formatBetting(this.Actions),
+		formatCards(this.Holes, this.Board), formatResults(this.Results),
+		strings.Join(this.Players, "|"))
+}
+
+func formatResults(results []float64) string {
+	s := make([]string, len(results))
+	for i, r := range results {
+		s[i] = strconv.FormatFloat(r, 'f', -1, 64)
 	}
-	return &ACPCLog{hands: strings.Split(string(log), "\n")}, nil
-}
-
-func (this *ACPCLog) Replay(c chan *ACPCLog) {
-	for _, hand := range this.hands {
-		if len(hand) > 4 && hand[:5] == "STATE" {
-			record := newRecord(hand)
-			this.cards = strings.Join(record.holes, " ")
-			this.action = ""
-			c <- this
-			for this.round < 4 {
-				for _, action := range record.actions[this.round] {
-					this.action = string(action)
-					this.cards = ""
-					c <- this
+	return strings.Join(s, "|")
+}
+
+// Seat returns the seat of the named player, or -1 if they did not play.
+func (this *Hand) Seat(name string) int {
+	for i, p := range this.Players {
+		if p == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// folded returns whether each seat folded. Seats act as they do with the ACPC
+// dealer: before the flop, the seat after the biggest blind acts first, or the
+// last seat if the blinds are not known, and after it the first seat does. A
+// seat that has gone all in is skipped, which is only known if the stacks are.
+func (this *Hand) folded() []bool {
+	n := len(this.Holes)
+	folded := make([]bool, n)
+	allIn := make([]bool, n)
+	spent := make([]float64, n)
+	first := n - 1
+	if len(this.Blinds) == n {
+		copy(spent, this.Blinds)
+		big := 0
+		for i, b := range this.Blinds {
+			if b >= this.Blinds[big] {
+				big = i
+			}
+		}
+		first = (big + 1) % n
+	}
+	// next returns the first seat from seat onwards that can act.
+	next := func(seat int) int {
+		for i := 0; i < n; i++ {
+			if j := (seat + i) % n; !folded[j] && !allIn[j] {
+				return j
+			}
+		}
+		return -1
+	}
+	for round, actions := range this.Actions {
+		if round > 0 {
+			first = 0
+		}
+		seat := next(first)
+		for _, a := range actions {
+			if seat < 0 {
+				break
+			}
+			switch a.Kind() {
+			case 'f':
+				folded[seat] = true
+			case 'c':
+				for _, chips := range spent {
+					spent[seat] = math.Max(spent[seat], chips)
 				}
-				this.round++
-				if this.round < 4 {
-					this.cards = record.board[this.round-1]
-					this.action = ""
-					c <- this
+			case 'r':
+				if size := a.Size(); size > 0 {
+					spent[seat] = float64(size)
 				}
 			}
+			if len(this.Stacks) == n && spent[seat] >= this.Stacks[seat] {
+				allIn[seat] = true
+			}
+			seat = next(seat + 1)
 		}
 	}
+	return folded
+}
+
+// Events returns the events of the hand as seen by the player in seat viewer,
+// in the same order a diff engine would send them. If the hand reached a
+// showdown, the last event gives the hole cards of every seat that did not
+// fold, since the log does not say which of them were shown.
+func (this *Hand) Events(viewer int) []interface{} {
+	folded := this.folded()
+	var folds int
+	for _, f := range folded {
+		if f {
+			folds++
+		}
+	}
+	holes := make([][]string, len(this.Holes))
+	for i, hole := range this.Holes {
+		if i == viewer || (!folded[i] && folds < len(this.Holes)-1) {
+			holes[i] = hole
+		}
+	}
+	m := &MatchState{
+		Position: viewer,
+		HandNum:  this.Num,
+		Actions:  this.Actions,
+		Holes:    holes,
+		Board:    this.Board,
+	}
+	// A state always follows nothing.
+	events, _ := m.Events(nil)
+	events[0] = &Players{Names: this.Players, Viewer: viewer}
+	return events
+}
+
+// An ACPCLog reads the hands of a match one at a time from a log in the ACPC
+// format. Comment lines, which start with "#" or ";", and the SCORE line are
+// skipped.
+type ACPCLog struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewACPCLog returns a log that reads hands from r.
+func NewACPCLog(r io.Reader) *ACPCLog {
+	return &ACPCLog{scanner: bufio.NewScanner(r)}
+}
+
+// Next returns the next hand in the log. It returns io.EOF when there are no
+// more hands.
+func (this *ACPCLog) Next() (*Hand, error) {
+	for this.scanner.Scan() {
+		this.line++
+		line := strings.TrimRight(this.scanner.Text(), "\r")
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' ||
+			strings.HasPrefix(line, "SCORE:") {
+			continue
+		}
+		h, err := ParseHand(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", this.line, err)
+		}
+		return h, nil
+	}
+	if err := this.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// An ACPCLogWriter writes the hands of a match to a log in the ACPC format,
+// which ACPCLog can read back.
+type ACPCLogWriter struct {
+	w      io.Writer
+	names  []string  // Every player, in the order they were first seen.
+	totals []float64 // The chips won by each of names.
+}
+
+// NewACPCLogWriter returns a log that writes hands to w.
+func NewACPCLogWriter(w io.Writer) *ACPCLogWriter {
+	return &ACPCLogWriter{w: w}
+}
+
+// Comment writes a line that readers of the log skip, such as a description
+// of the match.
+func (this *ACPCLogWriter) Comment(s string) error {
+	_, err := fmt.Fprintf(this.w, "# %s\n", s)
+	return err
+}
+
+// WriteHand writes the record of a hand and adds its results to each player's
+// score.
+func (this *ACPCLogWriter) WriteHand(h *Hand) error {
+next:
+	for i, p := range h.Players {
+		for j, name := range this.names {
+			if name == p {
+				this.totals[j] += h.Results[i]
+				continue next
+			}
+		}
+		this.names = append(this.names, p)
+		this.totals = append(this.totals, h.Results[i])
+	}
+	_, err := fmt.Fprintln(this.w, h)
+	return err
+}
+
+// WriteScore writes the total chips won by each player in the hands written
+// so far. It is written once, at the end of the match.
+func (this *ACPCLogWriter) WriteScore() error {
+	_, err := fmt.Fprintf(this.w, "SCORE:%s:%s\n", formatResults(this.totals),
+		strings.Join(this.names, "|"))
+	return err
 }
@@ -1,19 +1,79 @@
 package diff
 
 import (
-	"testing"
 	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
 )
 
-func TestReplay(_ *testing.T) {
-	log, err := NewACPCLog("test-game.log")
+func ExampleParseHand() {
+	h, err := ParseHand("STATE:0:rrrc/rc/rc/cc:5d5c|9hQd/8dAs8s/4h/6d:70|-70:Alice|Hobbes")
 	if err != nil {
 		panic(err)
 	}
-	c := make(chan *ACPCLog)
-	go log.Replay(c)
-	fmt.Println(log.hands[4])
-	for i := 0; i < 20; i++ {
-		fmt.Println(<-c)
+	fmt.Println(h.Num, h.Players, h.Actions, h.Holes, h.Board, h.Results)
+	fmt.Println(h.Events(1)[1:])
+	// Output:
+	// 0 [Alice Hobbes] [[r r r c] [r c] [r c] [c c]] [[5d 5c] [9h Qd]] [[] [8d As 8s] [4h] [6d]] [70 -70]
+	// [|9hQd r r r c 8dAs8s r c 4h r c 6d c c 5d5c|9hQd]
+}
+
+func TestACPCLog(test *testing.T) {
+	f, err := os.Open("test-game.log")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer f.Close()
+	log := NewACPCLog(f)
+	var n int
+	var total float64
+	for {
+		h, err := log.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			test.Fatal(err)
+		}
+		if h.Num != n {
+			test.Fatalf("Expected hand %d, but got %v\n", n, h)
+		}
+		if h2, err := ParseHand(h.String()); err != nil || !reflect.DeepEqual(h, h2) {
+			test.Fatalf("%v changed to %v %v after formatting\n", h, h2, err)
+		}
+		total += h.Results[h.Seat("Alice")]
+		n++
+	}
+	if n != 1000 || total != -24980 {
+		test.Fatalf("Expected Alice to lose 24980 in 1000 hands, but got %v in %d\n", total, n)
+	}
+
+	log = NewACPCLog(strings.NewReader("# comment\nSTATE:0:cx:AsKd|:0|0:a|b\n"))
+	if _, err := log.Next(); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		test.Fatalf("Expected an error on line 2, but got %v\n", err)
+	}
+}
+
+func TestHandEvents(test *testing.T) {
+	h, err := ParseHand("STATE:3:rcf/cr300f:4c8h|5d5c|AsKd/2c7d9h:-100|300|-200:a|b|c")
+	if err != nil {
+		test.Fatal(err)
+	}
+	expected := []interface{}{&Players{Names: []string{"a", "b", "c"}, Viewer: 2}, Cards("||AsKd"),
+		Action("r"), Action("c"), Action("f"), Cards("2c7d9h"),
+		Action("c"), Action("r300"), Action("f")}
+	if events := h.Events(2); !reflect.DeepEqual(events, expected) {
+		test.Fatalf("Expected %v, but got %v\n", expected, events)
+	}
+	// b folds before the showdown, so c does not see b's cards.
+	h, err = ParseHand("STATE:4:rcf/cc/cc/cc:4c8h|5d5c|AsKd/2c7d9h/Jc/Qh:-20|-10|30:a|b|c")
+	if err != nil {
+		test.Fatal(err)
+	}
+	events := h.Events(2)
+	if last := events[len(events)-1]; last != Cards("4c8h||AsKd") {
+		test.Fatalf("Expected the showdown to show a's and c's cards, but got %v\n", last)
 	}
 }
@@ -7,7 +7,13 @@
 // Players.
 package diff
 
-type Action  string   // f, c, r.
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+type Action  string   // f, c, r, or a no limit raise to a total such as r250.
 type Cards   string   // AsKd
 // The names of all the players in the current hand ordered by their
 // position relative to the dealer button. If the players' names are not
@@ -15,4 +21,135 @@ type Cards   string   // AsKd
 type Players struct {
 	Names  []string // The names of all the players.
 	Viewer int      // The offset into Names of the viewer.
+	Hand   *Hand    // The record of the hand, if it is replayed from a history.
+}
+
+// An Engine connects a player to a match. Events from the match are sent on
+// Events, which is closed when the match is over. Actions sent on Actions are
+// the player's responses to the latest event where it is the player's turn.
+type Engine interface {
+	Events() <-chan interface{}
+	Actions() chan<- string
+	// Errors gets the error that ended the match early, if there was one, and
+	// is closed after Events.
+	Errors() <-chan error
+	// Done is closed once the engine is closed. Actions should not be sent
+	// after it is closed.
+	Done() <-chan struct{}
+	Close() error
+}
+
+// channels holds what the engines share: the channels of an Engine, and how
+// the match ended. An engine embeds it and calls start before using it.
+type channels struct {
+	events   chan interface{}
+	actions  chan string
+	errs     chan error
+	done     chan struct{}
+	close    sync.Once
+	shutdown func() error // Releases the engine's connection when it is closed.
+	mu       sync.Mutex
+	err      error // Why the match ended early.
+}
+
+// start makes the channels and ends the match with ctx's error if ctx is done
+// first. shutdown is called once when the engine is closed.
+func (this *channels) start(ctx context.Context, shutdown func() error) {
+	this.events = make(chan interface{}, 3)
+	this.actions = make(chan string)
+	this.errs = make(chan error, 1)
+	this.done = make(chan struct{})
+	this.shutdown = shutdown
+	go func() {
+		select {
+		case <-ctx.Done():
+			this.fail(ctx.Err())
+		case <-this.done:
+		}
+	}()
+}
+
+// Events returns the channel on which events from the match are sent.
+func (this *channels) Events() <-chan interface{} {
+	return this.events
+}
+
+// Actions returns the channel on which to send the player's actions.
+func (this *channels) Actions() chan<- string {
+	return this.actions
+}
+
+// Errors returns a channel that gets the error that ended the match early, if
+// there was one, and is closed after Events.
+func (this *channels) Errors() <-chan error {
+	return this.errs
+}
+
+// Done returns a channel that is closed once the engine is closed. Actions
+// should not be sent after it is closed.
+func (this *channels) Done() <-chan struct{} {
+	return this.done
+}
+
+// Close ends the match and closes the connection.
+func (this *channels) Close() error {
+	var err error
+	this.close.Do(func() {
+		close(this.done)
+		err = this.shutdown()
+	})
+	return err
+}
+
+// fail ends the match because of err, unless it has already ended.
+func (this *channels) fail(err error) {
+	this.mu.Lock()
+	select {
+	case <-this.done:
+	default:
+		this.err = err
+	}
+	this.mu.Unlock()
+	this.Close()
+}
+
+// emit sends an event unless the match has ended.
+func (this *channels) emit(event interface{}) bool {
+	select {
+	case this.events <- event:
+		return true
+	case <-this.done:
+		return false
+	}
+}
+
+// finish closes the engine once its events have all been sent, and reports
+// why the match ended early, if it did.
+func (this *channels) finish() {
+	this.Close()
+	close(this.events)
+	this.mu.Lock()
+	if this.err != nil {
+		this.errs <- this.err
+	}
+	this.mu.Unlock()
+	close(this.errs)
+}
+
+// Kind returns the kind of action: 'f', 'c', or 'r'.
+func (this Action) Kind() byte {
+	if len(this) == 0 {
+		return 0
+	}
+	return this[0]
+}
+
+// Size returns the total chips a raise raises to, or 0 if the action does not
+// give a size.
+func (this Action) Size() int {
+	if len(this) < 2 {
+		return 0
+	}
+	n, _ := strconv.Atoi(string(this[1:]))
+	return n
 }
@@ -0,0 +1,161 @@
+package diff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// The JSON play protocol lets a player join a match over HTTP, so that web
+// pages and programs in other languages can play without speaking the ACPC
+// protocol over TCP. A player polls for the states of the match one at a time
+// with
+//
+//	GET /state?after=N
+//
+// where N is the Seq of the last state it got, 0 at first. The first poll
+// joins the match. The server answers with the next state as an HTTPState as
+// soon as there is one, or with the same Seq and no state if there was none
+// for a while. When it is the player's turn, it sends its action with
+//
+//	POST /action
+//
+// and an HTTPAction as the body. The match is over once a reply is Over.
+
+// An HTTPState is the reply to a poll for a state.
+type HTTPState struct {
+	Seq   int         `json:"seq"`             // The number of the state, from 1.
+	State *MatchState `json:"state,omitempty"` // The state, if there is a new one.
+	Over  bool        `json:"over"`            // If the match is over.
+}
+
+// An HTTPAction is an action sent in response to a state.
+type HTTPAction struct {
+	Seq    int    `json:"seq"`    // The number of the state responded to.
+	Action string `json:"action"` // The action, as in an ACPC response, such as "r250".
+}
+
+// HTTPOptions holds the settings for playing with the JSON play protocol.
+type HTTPOptions struct {
+	Client *http.Client // The client to make requests with, http.DefaultClient if nil.
+}
+
+// HTTP is a diff engine for playing with the JSON play protocol. Its channels
+// work as those of ACPC do.
+type HTTP struct {
+	channels
+	url    string
+	client *http.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+	seq    int // The number of the latest state.
+}
+
+// DialHTTP joins a match served with the JSON play protocol at url, such as
+// "http://localhost:8080".
+func DialHTTP(ctx context.Context, url string, opts HTTPOptions) (*HTTP, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("diff: %q is not an HTTP URL", url)
+	}
+	this := &HTTP{url: strings.TrimSuffix(url, "/"), client: opts.Client}
+	if this.client == nil {
+		this.client = http.DefaultClient
+	}
+	this.ctx, this.cancel = context.WithCancel(ctx)
+	this.start(ctx, func() error {
+		this.cancel()
+		return nil
+	})
+	go this.send()
+	go this.receive()
+	return this, nil
+}
+
+// do makes a request and decodes the JSON reply, if any, into reply.
+func (this *HTTP) do(method, path string, body, reply interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(this.ctx, method, this.url+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := this.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("diff: %s %s: %s", method, path, resp.Status)
+	}
+	if reply == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(reply)
+}
+
+func (this *HTTP) send() {
+	for {
+		select {
+		case a := <-this.actions:
+			this.mu.Lock()
+			seq := this.seq
+			this.mu.Unlock()
+			if err := this.do("POST", "/action", &HTTPAction{Seq: seq, Action: a}, nil); err != nil {
+				this.fail(err)
+				return
+			}
+		case <-this.done:
+			return
+		}
+	}
+}
+
+func (this *HTTP) receive() {
+	defer this.finish()
+	var prev *MatchState
+	var seq int
+	for {
+		var reply HTTPState
+		if err := this.do("GET", fmt.Sprintf("/state?after=%d", seq), nil, &reply); err != nil {
+			this.fail(err)
+			return
+		}
+		if reply.State != nil {
+			// Check the state as if it had come from an ACPC dealer.
+			state, err := ParseMatchState(reply.State.String())
+			if err != nil {
+				this.fail(err)
+				return
+			}
+			events, err := state.Events(prev)
+			if err != nil {
+				this.fail(err)
+				return
+			}
+			seq = reply.Seq
+			this.mu.Lock()
+			this.seq = seq
+			this.mu.Unlock()
+			for _, e := range events {
+				if !this.emit(e) {
+					return
+				}
+			}
+			prev = state
+		}
+		if reply.Over {
+			return
+		}
+	}
+}
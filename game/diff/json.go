@@ -0,0 +1,130 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// A JSONHand is a hand record that other tools can read easily. A history is
+// a file of JSON hands, one per line, such as
+//
+//	{"rules":"two player no limit Texas Hold'em","limit":false,"hand":7,
+//	 "players":["Alice","Bob"],"blinds":[100,50],"stacks":[20000,20000],
+//	 "holes":[["As","Ad"],["Kc","Kd"]],
+//	 "rounds":[{"board":[],"actions":[
+//	   {"seat":1,"action":"raise","amount":250,"total":300},
+//	   {"seat":0,"action":"call","amount":200,"total":300}]},
+//	  {"board":["2c","7d","9h"],"actions":[
+//	   {"seat":0,"action":"check","amount":0,"total":300},
+//	   {"seat":1,"action":"bet","amount":600,"total":900},
+//	   {"seat":0,"action":"fold","amount":0,"total":300}]}],
+//	 "results":[-300,300]}
+//
+// but all on one line. Seats are numbered as in a Hand. Each action gives the
+// chips put in with it and the seat's total in the pot after it.
+type JSONHand struct {
+	Rules   string      `json:"rules"`            // A description of the game.
+	Limit   bool        `json:"limit"`            // If raises are of a fixed size.
+	Num     int         `json:"hand"`             // The number of the hand.
+	Players []string    `json:"players"`          // The name of each seat.
+	Blinds  []float64   `json:"blinds"`           // The blind of each seat.
+	Stacks  []float64   `json:"stacks,omitempty"` // The starting chips of each seat, if limited.
+	Holes   [][]string  `json:"holes"`            // The hole cards of each seat, if known.
+	Rounds  []JSONRound `json:"rounds"`           // The rounds played.
+	Results []float64   `json:"results"`          // The chips won or lost by each seat.
+}
+
+// A JSONRound is the cards dealt and the actions taken in one round of a hand.
+type JSONRound struct {
+	Board   []string     `json:"board"`
+	Actions []JSONAction `json:"actions"`
+}
+
+// A JSONAction is an action taken by one seat. Action is "fold", "check",
+// "call", "bet", or "raise".
+type JSONAction struct {
+	Seat   int     `json:"seat"`
+	Action string  `json:"action"`
+	Amount float64 `json:"amount"` // The chips put in with the action.
+	Total  float64 `json:"total"`  // The seat's chips in the pot after it.
+}
+
+// Hand returns the hand the record is of.
+func (this *JSONHand) Hand() (*Hand, error) {
+	h := &Hand{
+		Num:     this.Num,
+		Players: this.Players,
+		Blinds:  this.Blinds,
+		Stacks:  this.Stacks,
+		Holes:   make([][]string, len(this.Holes)),
+		Results: this.Results,
+	}
+	if len(this.Holes) != len(this.Players) || len(this.Results) != len(this.Players) {
+		return nil, fmt.Errorf("diff: the number of seats differs in hand %d", this.Num)
+	}
+	for i, hole := range this.Holes {
+		if len(hole) > 0 {
+			h.Holes[i] = hole
+		}
+	}
+	for _, r := range this.Rounds {
+		var actions []Action
+		for _, a := range r.Actions {
+			switch a.Action {
+			case "fold":
+				actions = append(actions, "f")
+			case "check", "call":
+				actions = append(actions, "c")
+			case "bet", "raise":
+				if this.Limit {
+					actions = append(actions, "r")
+				} else {
+					actions = append(actions, Action(fmt.Sprintf("r%d", int(a.Total))))
+				}
+			default:
+				return nil, fmt.Errorf("diff: invalid action %q in hand %d", a.Action, this.Num)
+			}
+		}
+		var board []string
+		if len(r.Board) > 0 {
+			board = r.Board
+		}
+		h.Actions = append(h.Actions, actions)
+		h.Board = append(h.Board, board)
+	}
+	if len(h.Actions) == 0 {
+		return nil, fmt.Errorf("diff: no rounds in hand %d", this.Num)
+	}
+	return h, nil
+}
+
+// A JSONLog reads the hands of a history of JSON hands one at a time.
+type JSONLog struct {
+	dec *json.Decoder
+}
+
+// NewJSONLog returns a log that reads hands from r.
+func NewJSONLog(r io.Reader) *JSONLog {
+	return &JSONLog{dec: json.NewDecoder(r)}
+}
+
+// NextJSON returns the record of the next hand in the log. It returns io.EOF
+// when there are no more hands.
+func (this *JSONLog) NextJSON() (*JSONHand, error) {
+	h := new(JSONHand)
+	if err := this.dec.Decode(h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Next returns the next hand in the log. It returns io.EOF when there are no
+// more hands.
+func (this *JSONLog) Next() (*Hand, error) {
+	h, err := this.NextJSON()
+	if err != nil {
+		return nil, err
+	}
+	return h.Hand()
+}
@@ -0,0 +1,22 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONLog(test *testing.T) {
+	log := NewJSONLog(strings.NewReader(`{"limit":true,"hand":3,"players":["a","b"],"holes":[[],["As","Kd"]],"rounds":[{"board":[],"actions":[{"seat":1,"action":"raise"},{"seat":0,"action":"fold"}]}],"results":[-10,10]}
+{"hand":4,"players":["a","b"],"holes":[[],[]],"rounds":[{"board":[],"actions":[{"seat":1,"action":"shove"}]}],"results":[0,0]}
+`))
+	h, err := log.Next()
+	if err != nil {
+		test.Fatal(err)
+	}
+	if s := h.String(); s != "STATE:3:rf:|AsKd:-10|10:a|b" {
+		test.Fatalf("Expected hand 3, but got %s\n", s)
+	}
+	if _, err := log.Next(); err == nil {
+		test.Fatalf("Expected an error for an invalid action\n")
+	}
+}
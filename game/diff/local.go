@@ -0,0 +1,51 @@
+package diff
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrClosed is returned by Local.Action once the engine has been closed.
+var ErrClosed = errors.New("diff: the engine is closed")
+
+// A Local is a diff engine for a player in the same process as the dealer.
+// The dealer sends it events with Send and waits for the player's actions
+// with Action, so no protocol is spoken between them.
+type Local struct {
+	channels
+}
+
+// NewLocal returns an engine that the dealer feeds directly. The match ends
+// when End is called, the engine is closed, or ctx is done.
+func NewLocal(ctx context.Context) *Local {
+	this := &Local{}
+	this.start(ctx, func() error { return nil })
+	return this
+}
+
+// Send sends an event to the player, and returns false if the match has
+// already ended.
+func (this *Local) Send(event interface{}) bool {
+	return this.emit(event)
+}
+
+// Action waits for the player to respond to the latest event.
+func (this *Local) Action() (string, error) {
+	select {
+	case a := <-this.actions:
+		return a, nil
+	case <-this.done:
+		this.mu.Lock()
+		defer this.mu.Unlock()
+		if this.err != nil {
+			return "", this.err
+		}
+		return "", ErrClosed
+	}
+}
+
+// End ends the match once the player has been sent every event. It must be
+// called once, after the last Send.
+func (this *Local) End() {
+	this.finish()
+}
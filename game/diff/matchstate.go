@@ -0,0 +1,208 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"poker/cards"
+)
+
+// A MatchState is a parsed ACPC MATCHSTATE string, such as
+//
+//	MATCHSTATE:0:30:cc/r250c/r500:Ah9s|/7d2c5h/Qs
+//
+// which is the state seen by seat 0 in hand 30 after it checked, called a bet
+// of 250 on the flop, and faced a bet of 500 on the turn. Seats are numbered
+// from 0 in their order relative to the button.
+type MatchState struct {
+	Position int        `json:"position"` // The seat of the player viewing the state.
+	HandNum  int        `json:"hand"`     // The number of the hand in the match.
+	Actions  [][]Action `json:"actions"`  // The actions taken in each round so far.
+	Holes    [][]string `json:"holes"`    // The hole cards of each seat, nil if not seen.
+	Board    [][]string `json:"board"`    // The board cards dealt in each round so far.
+}
+
+// ParseMatchState parses a MATCHSTATE string.
+func ParseMatchState(s string) (*MatchState, error) {
+	fields := strings.Split(s, ":")
+	if len(fields) != 5 || fields[0] != "MATCHSTATE" {
+		return nil, fmt.Errorf("diff: invalid match state %q", s)
+	}
+	m := new(MatchState)
+	var err error
+	if m.Position, err = parseCount(fields[_POSITION]); err != nil {
+		return nil, fmt.Errorf("diff: invalid position in %q", s)
+	}
+	if m.HandNum, err = parseCount(fields[_HAND_NUM]); err != nil {
+		return nil, fmt.Errorf("diff: invalid hand number in %q", s)
+	}
+	if m.Actions, err = parseBetting(fields[_BETS]); err != nil {
+		return nil, fmt.Errorf("diff: %v in %q", err, s)
+	}
+	if m.Holes, m.Board, err = parseCards(fields[_CARDS]); err != nil {
+		return nil, fmt.Errorf("diff: %v in %q", err, s)
+	}
+	if m.Position >= len(m.Holes) {
+		return nil, fmt.Errorf("diff: position %d is not a seat in %q", m.Position, s)
+	}
+	if len(m.Board) != len(m.Actions) {
+		return nil, fmt.Errorf("diff: the cards and actions are for different rounds in %q", s)
+	}
+	return m, nil
+}
+
+// String formats the state as a MATCHSTATE string.
+func (this *MatchState) String() string {
+	return fmt.Sprintf("MATCHSTATE:%d:%d:%s:%s", this.Position, this.HandNum,
+		formatBetting(this.Actions), formatCards(this.Holes, this.Board))
+}
+
+// Round returns the current round.
+func (this *MatchState) Round() int {
+	return len(this.Actions) - 1
+}
+
+// parseCount parses a number that can not be negative.
+func parseCount(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err == nil && (n < 0 || s[0] == '+') {
+		err = fmt.Errorf("%q is not a count", s)
+	}
+	return n, err
+}
+
+// parseBetting parses the actions in each round, which are separated by "/".
+func parseBetting(s string) ([][]Action, error) {
+	rounds := strings.Split(s, "/")
+	actions := make([][]Action, len(rounds))
+	for i, r := range rounds {
+		for len(r) > 0 {
+			n := 1
+			switch r[0] {
+			case 'f', 'c':
+			case 'r':
+				for n < len(r) && r[n] >= '0' && r[n] <= '9' {
+					n++
+				}
+			default:
+				return nil, fmt.Errorf("invalid action %q", r[0])
+			}
+			actions[i] = append(actions[i], Action(r[:n]))
+			r = r[n:]
+		}
+	}
+	return actions, nil
+}
+
+func formatBetting(actions [][]Action) string {
+	rounds := make([]string, len(actions))
+	for i, r := range actions {
+		for _, a := range r {
+			rounds[i] += string(a)
+		}
+	}
+	return strings.Join(rounds, "/")
+}
+
+// parseCards parses the hole cards of each seat, separated by "|", followed by
+// the board cards dealt in each round after the first, each after a "/".
+func parseCards(s string) ([][]string, [][]string, error) {
+	rounds := strings.Split(s, "/")
+	seats := strings.Split(rounds[0], "|")
+	holes := make([][]string, len(seats))
+	board := make([][]string, len(rounds))
+	var err error
+	for i, h := range seats {
+		if holes[i], err = splitCards(h); err != nil {
+			return nil, nil, err
+		}
+	}
+	for i := 1; i < len(rounds); i++ {
+		if board[i], err = splitCards(rounds[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return holes, board, nil
+}
+
+func formatCards(holes, board [][]string) string {
+	seats := make([]string, len(holes))
+	for i, h := range holes {
+		seats[i] = strings.Join(h, "")
+	}
+	s := strings.Join(seats, "|")
+	for i := 1; i < len(board); i++ {
+		s += "/" + strings.Join(board[i], "")
+	}
+	return s
+}
+
+// splitCards splits a string of cards such as "AsKd" into single cards.
+func splitCards(s string) ([]string, error) {
+	if len(s)%2 != 0 {
+		return nil, fmt.Errorf("invalid cards %q", s)
+	}
+	var c []string
+	for i := 0; i < len(s); i += 2 {
+		if strings.IndexByte(cards.Ranks, s[i]) < 0 || strings.IndexByte(cards.Suits, s[i+1]) < 0 {
+			return nil, fmt.Errorf("invalid card %q", s[i:i+2])
+		}
+		c = append(c, s[i:i+2])
+	}
+	return c, nil
+}
+
+// Events returns the events that update a game from the state prev to this
+// state, in the order they happened: an Action for each new action, Cards
+// with the board cards of each new round, and Cards with the hole cards of
+// every seat, separated by "|", once they are revealed at the showdown. If
+// prev is nil or from another hand, the events start with the new hand's
+// Players and the viewer's hole cards.
+func (this *MatchState) Events(prev *MatchState) ([]interface{}, error) {
+	var events []interface{}
+	if prev == nil || prev.HandNum != this.HandNum {
+		holes := make([][]string, len(this.Holes))
+		holes[this.Position] = this.Holes[this.Position]
+		events = append(events, &Players{Viewer: this.Position},
+			Cards(formatCards(holes, nil)))
+		prev = &MatchState{
+			Position: this.Position,
+			HandNum:  this.HandNum,
+			Actions:  [][]Action{nil},
+			Holes:    holes,
+			Board:    [][]string{nil},
+		}
+	}
+	if len(this.Actions) < len(prev.Actions) || len(this.Holes) != len(prev.Holes) {
+		return nil, fmt.Errorf("diff: %v does not follow %v", this, prev)
+	}
+	for r, actions := range this.Actions {
+		var seen []Action
+		if r < len(prev.Actions) {
+			seen = prev.Actions[r]
+		} else {
+			// New round.
+			events = append(events, Cards(strings.Join(this.Board[r], "")))
+		}
+		if len(actions) < len(seen) {
+			return nil, fmt.Errorf("diff: %v does not follow %v", this, prev)
+		}
+		for i, a := range actions {
+			if i < len(seen) && a != seen[i] {
+				return nil, fmt.Errorf("diff: %v does not follow %v", this, prev)
+			}
+			if i >= len(seen) {
+				events = append(events, a)
+			}
+		}
+	}
+	// Hole cards revealed.
+	for i, h := range this.Holes {
+		if len(h) > len(prev.Holes[i]) {
+			events = append(events, Cards(formatCards(this.Holes, nil)))
+			break
+		}
+	}
+	return events, nil
+}
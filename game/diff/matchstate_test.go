@@ -0,0 +1,95 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func ExampleParseMatchState() {
+	m, err := ParseMatchState("MATCHSTATE:0:30:cc/r250c/r500:Ah9s|/7d2c5h/Qs")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(m.Position, m.HandNum, m.Round(), m.Actions, m.Holes, m.Board)
+	fmt.Println(m.Actions[2][0].Kind() == 'r', m.Actions[2][0].Size())
+	fmt.Println(m)
+	// Output:
+	// 0 30 2 [[c c] [r250 c] [r500]] [[Ah 9s] []] [[] [7d 2c 5h] [Qs]]
+	// true 500
+	// MATCHSTATE:0:30:cc/r250c/r500:Ah9s|/7d2c5h/Qs
+}
+
+func TestParseMatchState(test *testing.T) {
+	bad := []string{
+		"MATCHSTATE:0:30:cc/r250c/r500:Ah9s|/7d2c5h",
+		"MATCHSTATE:2:30:cc:Ah9s|",
+		"MATCHSTATE:0:-1:cc:Ah9s|",
+		"MATCHSTATE:0:30:cx:Ah9s|",
+		"MATCHSTATE:0:30:cc:Ah9|",
+		"MATCHSTATE:0:30:cc:Ah9x|",
+		"STATE:0:30:cc:Ah9s|",
+	}
+	for _, s := range bad {
+		if m, err := ParseMatchState(s); err == nil {
+			test.Fatalf("Expected an error parsing %q, but got %+v\n", s, m)
+		}
+	}
+}
+
+func FuzzParseMatchState(f *testing.F) {
+	f.Add("MATCHSTATE:0:30:cc/r250c/r500:Ah9s|/7d2c5h/Qs")
+	f.Add("MATCHSTATE:1:0:rrrc/rc/rc/cc:5d5c|9hQd/8dAs8s/4h/6d")
+	f.Add("MATCHSTATE:2:7:rcf:||4c8h")
+	f.Add("MATCHSTATE:0:1:r20000c///:AsAd|KcKd/2c7d9h/Jc/Qh")
+	f.Fuzz(func(test *testing.T, s string) {
+		m, err := ParseMatchState(s)
+		if err != nil {
+			return
+		}
+		m2, err := ParseMatchState(m.String())
+		if err != nil {
+			test.Fatalf("Could not parse %q formatted from %q: %v", m, s, err)
+		}
+		if !reflect.DeepEqual(m, m2) || m.String() != m2.String() {
+			test.Fatalf("%q changed to %q after formatting", s, m2)
+		}
+	})
+}
+
+func TestMatchStateEvents(test *testing.T) {
+	states := []string{
+		"MATCHSTATE:2:7:r:||4c8h",
+		"MATCHSTATE:2:7:rcf/:||4c8h/2c7d9h",
+		"MATCHSTATE:2:7:rcf/r250c/:||4c8h/2c7d9h/Jc",
+		"MATCHSTATE:2:7:rcf/r250c/cc/cc:5d5c||4c8h/2c7d9h/Jc/Qh",
+		"MATCHSTATE:0:8:r20000c///:AsAd|KcKd/2c7d9h/Jc/Qh",
+	}
+	expected := [][]interface{}{
+		{&Players{Viewer: 2}, Cards("||4c8h"), Action("r")},
+		{Action("c"), Action("f"), Cards("2c7d9h")},
+		{Action("r250"), Action("c"), Cards("Jc")},
+		{Action("c"), Action("c"), Cards("Qh"), Action("c"), Action("c"), Cards("5d5c||4c8h")},
+		{&Players{Viewer: 0}, Cards("AsAd|"), Action("r20000"), Action("c"),
+			Cards("2c7d9h"), Cards("Jc"), Cards("Qh"), Cards("AsAd|KcKd")},
+	}
+	var prev *MatchState
+	for i, s := range states {
+		m, err := ParseMatchState(s)
+		if err != nil {
+			test.Fatal(err)
+		}
+		events, err := m.Events(prev)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if !reflect.DeepEqual(events, expected[i]) {
+			test.Fatalf("Expected %v after %q, but got %v\n", expected[i], s, events)
+		}
+		prev = m
+	}
+	m, _ := ParseMatchState("MATCHSTATE:0:8:r20000f:AsAd|")
+	if _, err := m.Events(prev); err == nil {
+		test.Fatalf("Expected an error for a state that does not follow %v\n", prev)
+	}
+}
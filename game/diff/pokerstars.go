@@ -0,0 +1,378 @@
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A PokerStarsLog reads the hands of a PokerStars hand history one at a time.
+// Amounts with a currency symbol, as in cash games, are read in cents, so that
+// raises can be given in whole chips. Rake is not counted in the results.
+type PokerStarsLog struct {
+	scanner *bufio.Scanner
+	next    string // The first line of the next hand, if it has been read.
+}
+
+// NewPokerStarsLog returns a log that reads hands from r.
+func NewPokerStarsLog(r io.Reader) *PokerStarsLog {
+	return &PokerStarsLog{scanner: bufio.NewScanner(r)}
+}
+
+// Next returns the next hand in the history. It returns io.EOF when there are
+// no more hands.
+func (this *PokerStarsLog) Next() (*Hand, error) {
+	var lines []string
+	if this.next != "" {
+		lines = append(lines, this.next)
+		this.next = ""
+	}
+	for this.scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(this.scanner.Text(), "\ufeff"))
+		if strings.HasPrefix(line, "PokerStars ") {
+			if len(lines) > 0 {
+				this.next = line
+				break
+			}
+		} else if len(lines) == 0 || line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		if err := this.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return ParsePokerStars(lines)
+}
+
+// A psHand holds what is known about a PokerStars hand while it is parsed.
+type psHand struct {
+	*Hand
+	limit  bool
+	seats  map[int]int // The seat of each table seat number.
+	names  []string    // The players, longest name first.
+	spent  []float64   // The chips each seat has put in the pot, but for antes.
+	antes  []float64   // The antes each seat has put in the pot.
+	street []float64   // The chips each seat has bet this round.
+	won    []float64   // The chips each seat has collected.
+}
+
+// ParsePokerStars parses the lines of one hand from a PokerStars hand history,
+// from the "PokerStars Hand #" line to the end of the summary.
+func ParsePokerStars(lines []string) (*Hand, error) {
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("diff: incomplete PokerStars hand")
+	}
+	header := lines[0]
+	i := strings.Index(header, "#")
+	j := strings.Index(header, ":")
+	if i < 0 || j < i {
+		return nil, fmt.Errorf("diff: invalid PokerStars hand %q", header)
+	}
+	num, err := strconv.Atoi(header[i+1 : j])
+	if err != nil {
+		return nil, fmt.Errorf("diff: invalid hand number in %q", header)
+	}
+	h := &psHand{
+		Hand:  &Hand{Num: num, Actions: [][]Action{nil}, Board: [][]string{nil}},
+		limit: strings.Contains(header, " Limit") && !strings.Contains(header, "No Limit") && !strings.Contains(header, "Pot Limit"),
+	}
+	button, err := h.parseSeats(lines[1:])
+	if err != nil {
+		return nil, fmt.Errorf("diff: %v in hand %d", err, num)
+	}
+	if err := h.parseButton(lines[1], button); err != nil {
+		return nil, fmt.Errorf("diff: %v in hand %d", err, num)
+	}
+	summary := false
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, "*** ") {
+			summary = summary || strings.HasPrefix(line, "*** SUMMARY")
+			err = h.parseStreet(line)
+		} else if summary {
+			err = h.parseSummary(line)
+		} else {
+			err = h.parseLine(line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("diff: %v in hand %d", err, num)
+		}
+	}
+	h.Results = make([]float64, len(h.Players))
+	for i := range h.Results {
+		h.Results[i] = h.won[i] - h.spent[i] - h.antes[i]
+	}
+	return h.Hand, nil
+}
+
+// parseSeats reads the players in the seat lines before the cards are dealt.
+// It returns the table seat numbers in order.
+func (this *psHand) parseSeats(lines []string) ([]int, error) {
+	var numbers []int
+	names := make(map[int]string)
+	stacks := make(map[int]float64)
+	for _, line := range lines {
+		if strings.HasPrefix(line, "*** ") {
+			break
+		}
+		if !strings.HasPrefix(line, "Seat ") || strings.HasSuffix(line, "is sitting out") {
+			continue
+		}
+		colon := strings.Index(line, ": ")
+		open := strings.LastIndex(line, " (")
+		if colon < 0 || open < colon || !strings.Contains(line[open:], " in chips") {
+			continue
+		}
+		n, err := strconv.Atoi(line[len("Seat "):colon])
+		if err != nil {
+			return nil, fmt.Errorf("invalid seat %q", line)
+		}
+		chips := strings.Fields(line[open+2:])[0]
+		stack, err := parseAmount(chips)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, n)
+		names[n] = line[colon+2 : open]
+		stacks[n] = stack
+	}
+	if len(numbers) < 2 {
+		return nil, fmt.Errorf("fewer than 2 players")
+	}
+	sort.Ints(numbers)
+	for _, n := range numbers {
+		this.Players = append(this.Players, names[n])
+		this.Stacks = append(this.Stacks, stacks[n])
+	}
+	return numbers, nil
+}
+
+// parseButton orders the seats from the one after the button, so that the
+// button is last.
+func (this *psHand) parseButton(table string, numbers []int) error {
+	i := strings.Index(table, "Seat #")
+	if i < 0 {
+		return fmt.Errorf("no button in %q", table)
+	}
+	button, err := strconv.Atoi(strings.Fields(table[i+len("Seat #"):])[0])
+	if err != nil {
+		return fmt.Errorf("invalid button in %q", table)
+	}
+	first := 0
+	for first < len(numbers) && numbers[first] <= button {
+		first++
+	}
+	n := len(numbers)
+	players, stacks := this.Players, this.Stacks
+	this.Players, this.Stacks = make([]string, n), make([]float64, n)
+	this.seats = make(map[int]int)
+	for i := range numbers {
+		k := (first + i) % n
+		this.Players[i], this.Stacks[i] = players[k], stacks[k]
+		this.seats[numbers[k]] = i
+	}
+	this.names = append([]string(nil), this.Players...)
+	sort.Slice(this.names, func(i, j int) bool {
+		return len(this.names[i]) > len(this.names[j])
+	})
+	this.Blinds = make([]float64, n)
+	this.Holes = make([][]string, n)
+	this.spent = make([]float64, n)
+	this.antes = make([]float64, n)
+	this.street = make([]float64, n)
+	this.won = make([]float64, n)
+	return nil
+}
+
+// seat returns the seat of the player whose name starts line, and the rest of
+// the line after the name, or -1 if the line is not about a player.
+func (this *psHand) seat(line string) (int, string) {
+	for _, name := range this.names {
+		if strings.HasPrefix(line, name) {
+			for i, p := range this.Players {
+				if p == name {
+					return i, line[len(name):]
+				}
+			}
+		}
+	}
+	return -1, ""
+}
+
+// parseStreet starts a new round of betting at a "*** FLOP ***" line or the
+// like.
+func (this *psHand) parseStreet(line string) error {
+	switch {
+	case strings.HasPrefix(line, "*** FLOP"), strings.HasPrefix(line, "*** TURN"),
+		strings.HasPrefix(line, "*** RIVER"):
+	default:
+		return nil
+	}
+	i := strings.LastIndex(line, "[")
+	j := strings.LastIndex(line, "]")
+	if i < 0 || j < i {
+		return fmt.Errorf("no cards in %q", line)
+	}
+	cards, err := parseCardList(line[i+1 : j])
+	if err != nil {
+		return err
+	}
+	this.Actions = append(this.Actions, nil)
+	this.Board = append(this.Board, cards)
+	for i := range this.street {
+		this.street[i] = 0
+	}
+	return nil
+}
+
+// parseLine parses a line of the hand before the summary.
+func (this *psHand) parseLine(line string) error {
+	if strings.HasPrefix(line, "Dealt to ") {
+		s, rest := this.seat(line[len("Dealt to "):])
+		if s < 0 {
+			return fmt.Errorf("unknown player in %q", line)
+		}
+		return this.parseHoles(s, rest)
+	}
+	if strings.HasPrefix(line, "Uncalled bet (") {
+		i := strings.Index(line, ")")
+		s, _ := this.seat(strings.TrimPrefix(line[i+1:], " returned to "))
+		chips, err := parseAmount(line[len("Uncalled bet ("):i])
+		if err != nil || s < 0 {
+			return fmt.Errorf("invalid line %q", line)
+		}
+		this.spent[s] -= chips
+		this.street[s] -= chips
+		return nil
+	}
+	s, rest := this.seat(line)
+	if s < 0 {
+		return nil
+	}
+	if strings.HasPrefix(rest, " collected ") {
+		chips, err := parseAmount(strings.Fields(rest)[1])
+		if err != nil {
+			return err
+		}
+		this.won[s] += chips
+		return nil
+	}
+	if !strings.HasPrefix(rest, ": ") {
+		return nil
+	}
+	fields := strings.Fields(strings.TrimSuffix(rest[2:], " and is all-in"))
+	if len(fields) == 0 {
+		return nil
+	}
+	last := fields[len(fields)-1]
+	round := len(this.Actions) - 1
+	switch fields[0] {
+	case "posts":
+		chips, err := parseAmount(last)
+		if err != nil {
+			return err
+		}
+		if fields[1] == "the" { // Antes are not bets.
+			this.antes[s] += chips
+			break
+		}
+		this.spent[s] += chips
+		this.street[s] += chips
+		this.Blinds[s] += chips
+	case "folds":
+		this.Actions[round] = append(this.Actions[round], "f")
+	case "checks":
+		this.Actions[round] = append(this.Actions[round], "c")
+	case "calls":
+		chips, err := parseAmount(last)
+		if err != nil {
+			return err
+		}
+		this.spent[s] += chips
+		this.street[s] += chips
+		this.Actions[round] = append(this.Actions[round], "c")
+	case "bets", "raises":
+		// "bets $x" or "raises $x to $y", where y is the total bet this round.
+		chips, err := parseAmount(last)
+		if err != nil {
+			return err
+		}
+		if fields[0] == "bets" {
+			chips += this.street[s]
+		}
+		this.spent[s] += chips - this.street[s]
+		this.street[s] = chips
+		a := Action("r")
+		if !this.limit {
+			a = Action(fmt.Sprintf("r%d", int(math.Round(this.spent[s]))))
+		}
+		this.Actions[round] = append(this.Actions[round], a)
+	case "shows":
+		return this.parseHoles(s, rest)
+	}
+	return nil
+}
+
+// parseSummary gets the hole cards shown or mucked from a summary line.
+func (this *psHand) parseSummary(line string) error {
+	if !strings.HasPrefix(line, "Seat ") ||
+		!(strings.Contains(line, " showed [") || strings.Contains(line, " mucked [")) {
+		return nil
+	}
+	colon := strings.Index(line, ":")
+	n, err := strconv.Atoi(line[len("Seat "):colon])
+	if err != nil {
+		return fmt.Errorf("invalid seat %q", line)
+	}
+	s, ok := this.seats[n]
+	if !ok {
+		return fmt.Errorf("unknown seat %q", line)
+	}
+	return this.parseHoles(s, line)
+}
+
+// parseHoles sets the hole cards of seat s from the first [...] in line.
+func (this *psHand) parseHoles(s int, line string) error {
+	i := strings.Index(line, "[")
+	j := strings.Index(line, "]")
+	if i < 0 || j < i {
+		return fmt.Errorf("no cards in %q", line)
+	}
+	cards, err := parseCardList(line[i+1 : j])
+	if err != nil {
+		return err
+	}
+	this.Holes[s] = cards
+	return nil
+}
+
+// parseCardList parses cards separated by spaces, such as "Ah Kd".
+func parseCardList(s string) ([]string, error) {
+	return splitCards(strings.Replace(s, " ", "", -1))
+}
+
+// parseAmount parses a number of chips, such as "1,500", or an amount of
+// money, such as "$0.25", which it returns in cents.
+func parseAmount(s string) (float64, error) {
+	s = strings.Trim(s, "(),")
+	cash := false
+	for len(s) > 0 && (s[0] < '0' || s[0] > '9') {
+		s = s[1:]
+		cash = true
+	}
+	f, err := strconv.ParseFloat(strings.Replace(s, ",", "", -1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if cash {
+		f = math.Round(f * 100)
+	}
+	return f, nil
+}
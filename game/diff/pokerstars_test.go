@@ -0,0 +1,43 @@
+package diff
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestPokerStarsLog(test *testing.T) {
+	f, err := os.Open("test-pokerstars.txt")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer f.Close()
+	expected := []struct {
+		state          string
+		blinds, stacks []float64
+	}{
+		{"STATE:212345678901:r6fc/cr16r36c/r86c/cc:|AhKd|9s9c/2c7d9h/Jc/6d:-1|-86|87:Carol|Alice|Bob",
+			[]float64{1, 2, 0}, []float64{180, 200, 250}},
+		{"STATE:212345678902:r6c/cr14f:|QsQd/3c4dKh:-6|6:Bob|Alice",
+			[]float64{2, 1}, []float64{150, 286}},
+		// Antes are in the results, but not in the raises.
+		{"STATE:212345678903:r6c/r16f:|QsQd/3c4dKh:7|-7:Bob|Alice",
+			[]float64{2, 1}, []float64{150, 286}},
+	}
+	log := NewPokerStarsLog(f)
+	for _, e := range expected {
+		h, err := log.Next()
+		if err != nil {
+			test.Fatal(err)
+		}
+		if h.String() != e.state || !reflect.DeepEqual(h.Blinds, e.blinds) ||
+			!reflect.DeepEqual(h.Stacks, e.stacks) {
+			test.Fatalf("Expected %s %v %v, but got %s %v %v\n", e.state, e.blinds, e.stacks,
+				h, h.Blinds, h.Stacks)
+		}
+	}
+	if h, err := log.Next(); err != io.EOF {
+		test.Fatalf("Expected the end of the history, but got %v %v\n", h, err)
+	}
+}
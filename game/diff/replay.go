@@ -0,0 +1,64 @@
+package diff
+
+import (
+	"context"
+	"io"
+)
+
+// A Replay is a diff engine that replays the hands of a hand history as if
+// they were being played. The player called name is the viewer of each hand,
+// and hands without it are skipped. The actions sent on Actions are ignored,
+// since the hands have already been played. The Players event that starts
+// each hand gives the record of the hand.
+type Replay struct {
+	channels
+	log  HandReader
+	name string
+}
+
+// NewReplay returns an engine that replays the hands read from log to the
+// player called name.
+func NewReplay(log HandReader, name string) *Replay {
+	this := &Replay{log: log, name: name}
+	this.start(context.Background(), func() error { return nil })
+	go this.ignore()
+	go this.receive()
+	return this
+}
+
+// ignore takes the player's actions until the engine is closed.
+func (this *Replay) ignore() {
+	for {
+		select {
+		case <-this.actions:
+		case <-this.done:
+			return
+		}
+	}
+}
+
+func (this *Replay) receive() {
+	defer this.finish()
+	for {
+		h, err := this.log.Next()
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			this.fail(err)
+			return
+		}
+		viewer := h.Seat(this.name)
+		if viewer < 0 {
+			continue
+		}
+		events := h.Events(viewer)
+		players := *events[0].(*Players)
+		players.Hand = h
+		events[0] = &players
+		for _, e := range events {
+			if !this.emit(e) {
+				return
+			}
+		}
+	}
+}
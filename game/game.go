@@ -1,15 +1,23 @@
 // Package game keeps track of basic poker game information. Any Player may use
-// the game.Play function in order to play a game.
+// the game.Play function to play with an ACPC dealer, or PlayWith to play
+// through any diff engine.
 package game
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"math"
 	"net"
+	"strconv"
+	"strings"
+	"time"
 
+	"poker/cards"
 	"poker/game/diff"
 )
 
+// The rounds of Texas Hold'em. Games with fewer rounds reach the showdown
+// after their last round, when Round equals NumRounds.
 const (
 	PreFlop = iota
 	Flop
@@ -18,6 +26,9 @@ const (
 	Showdown
 )
 
+// RoundNames are the names of the rounds of Texas Hold'em.
+var RoundNames = [...]string{"preflop", "flop", "turn", "river"}
+
 func splitCards(s string) []string {
 	a := make([]string, len(s)/2)
 	na := 0
@@ -30,44 +41,113 @@ func splitCards(s string) []string {
 	return a[:na]
 }
 
+// setHands sets the hole cards of each seat from cards such as "|9hQd" that
+// separate the seats with "|". Cards without a "|" are the viewer's.
+func (this *Game) setHands(s string) {
+	this.Hands = make([][]string, len(this.Actions))
+	if !strings.Contains(s, "|") {
+		if this.Players != nil && this.Viewer >= 0 && this.Viewer < len(this.Hands) {
+			this.Hands[this.Viewer] = splitCards(s)
+		}
+		return
+	}
+	for i, h := range strings.Split(s, "|") {
+		if i < len(this.Hands) && h != "" {
+			this.Hands[i] = splitCards(h)
+		}
+	}
+}
+
 type Player interface {
 	Play(g *Game) (action string)
 	Observe(g *Game)
 }
 
+// A HandStarter is a Player that wants to know when a hand starts.
+// HandStarted is called with the new hand before Observe.
+type HandStarter interface {
+	HandStarted(g *Game)
+}
+
+// A HandEnder is a Player that wants to know how each hand ended. HandEnded is
+// called after the last event of a hand has been observed, with the chips won
+// or lost by each seat. g.Hands holds the hole cards seen at the showdown.
+type HandEnder interface {
+	HandEnded(g *Game, results []float64)
+}
+
+// A MatchEnder is a Player that wants to know when the match is over, such as
+// to save what it has learned. err is the error that ended the match early,
+// if there was one.
+type MatchEnder interface {
+	MatchEnded(err error)
+}
+
+// handOver reports whether the hand has been played out.
+func (this *Game) handOver() bool {
+	return this.Round >= this.NumRounds() || this.NumActive() < 2
+}
+
+// dispatch passes an event to p, calling its optional callbacks as hands start
+// and end, and returns p's action if it is p's turn. playing is whether a
+// hand is being played and is updated. p plays on clock's time, and the calls
+// to p wait on clock while p is late.
+func (this *Game) dispatch(p Player, event interface{}, playing *bool, clock *clock) (action string, ok bool) {
+	this.Update(event)
+	if _, start := event.(*diff.Players); start {
+		*playing = true
+		clock.startHand()
+		if s, ok := p.(HandStarter); ok {
+			clock.call(this, s.HandStarted)
+		}
+	}
+	if this.Actor == this.Viewer {
+		action, ok = clock.play(p, this), true
+	} else {
+		clock.call(this, p.Observe)
+	}
+	if *playing && this.handOver() {
+		*playing = false
+		if e, ok := p.(HandEnder); ok {
+			results := this.Payoffs(this.Hands)
+			clock.call(this, func(g *Game) { e.HandEnded(g, results) })
+		}
+	}
+	return action, ok
+}
+
 type Game struct {
-	Round     int          // 0-4: pre-flop, flop, turn, river, showdown.
-	Bets      [4][]float64 // The chips put in for each player for each round.
+	Round     int          // 0 to NumRounds, where NumRounds is the showdown.
+	Bets      [][]float64  // The chips put in for each player for each round.
 	Holes     []string     // All of the viewable hole cards.
+	Hands     [][]string   // The viewable hole cards of each seat.
 	Board     []string     // All of the board cards.
 	Raises    int          // The number of raises this round.
+	LastRaise float64      // The size of the last full raise this round.
 	Actions   []byte       // The last action taken by each player.
 	Actor     int          // The player whose turn it is to act.
+	LastActor int          // The player who took the latest action, or -1 after other events.
 	*Rules                 // The set of rules to use to play the game.
 	Event     interface{}  // The most recent event
+	Deadline  time.Time    // When the player must act by while it plays, if there is a time limit.
 	*diff.Players
+	undo      []step       // The steps to undo, most recent last.
+	redo      []step       // The steps undone, most recent last.
 }
 
-// Makes a partial copy of the Game that is specifically suited for creating
-// game trees.
+// Copy returns a copy of the Game that shares nothing with the original but
+// its Rules, which never change. The history kept by Apply is not copied, so
+// the copy has nothing to undo or redo.
 func (this *Game) Copy() *Game {
-	g := new(Game)
-	g.Round = this.Round
-	for i := range g.Bets {
-		g.Bets[i] = make([]float64, len(this.Bets[0]))
-		copy(g.Bets[i], this.Bets[i])
-	}
-	g.Holes = make([]string, len(this.Holes))
-	copy(g.Holes, this.Holes)
-	g.Board = make([]string, len(this.Board))
-	copy(g.Board, this.Board)
-	g.Raises = this.Raises
-	g.Actions = make([]byte, len(this.Actions))
-	copy(g.Actions, this.Actions)
-	g.Actor = this.Actor
-	g.Rules = this.Rules
-	//Event     interface{}
-	g.Players = this.Players
+	g := NewGameRules(this.Rules)
+	g.SetState(this.State())
+	g.Event = this.Event
+	g.Deadline = this.Deadline
+	if this.Players != nil {
+		p := *this.Players
+		p.Names = append([]string(nil), p.Names...)
+		g.Players = &p
+	}
 	return g
 }
 
@@ -76,13 +156,19 @@ func NewGame(rules string) (*Game, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewGameRules(r), nil
+}
+
+// NewGameRules returns a new Game played with the given rules.
+func NewGameRules(r *Rules) *Game {
 	g := new(Game)
 	g.Rules = r
 	g.Actions = make([]byte, r.numPlayers)
+	g.Bets = make([][]float64, r.numRounds)
 	for i := range g.Bets {
 		g.Bets[i] = make([]float64, r.numPlayers)
 	}
-	return g, nil
+	return g
 }
 
 func (this *Game) String() string {
@@ -110,24 +196,96 @@ func (this *Game) LegalActions() string {
 	if this.CallAmt() > 0 {
 		actions += "f"
 	}
-	if this.Raises < this.maxRaises[this.Round] {
+	if this.Raises < this.MaxRaises(this.Round) &&
+		(this.Limit() || this.Spent(this.Actor)+this.CallAmt() < this.stackOf(this.Actor)) {
 		actions += "r"
 	}
 	return actions
 }
 
-func (this *Game) CallAmt() float64 {
+// maxBet returns the most chips any player has put in this round.
+func (this *Game) maxBet() float64 {
 	var max float64
 	for _, chips := range this.Bets[this.Round] {
 		if chips > max {
 			max = chips
 		}
 	}
-	return max - this.Bets[this.Round][this.Actor]
+	return max
 }
 
+func (this *Game) CallAmt() float64 {
+	call := this.maxBet() - this.Bets[this.Round][this.Actor]
+	if !this.Limit() {
+		if left := this.stackOf(this.Actor) - this.Spent(this.Actor); call > left {
+			return left
+		}
+	}
+	return call
+}
+
+// RaiseAmt returns the chips the actor must put in this round to make the
+// smallest legal raise.
 func (this *Game) RaiseAmt() float64 {
-	return this.CallAmt() + this.raiseSize[this.Round]
+	if this.Limit() {
+		return this.CallAmt() + this.RaiseSize(this.Round)
+	}
+	return this.MinRaise() - this.Spent(this.Actor)
+}
+
+// raiseBy returns the least a no limit raise must add to the current bet: the
+// last full raise this round or the big blind, whichever is bigger.
+func (this *Game) raiseBy() float64 {
+	size := this.LastRaise
+	for _, b := range this.blind {
+		if b > size {
+			size = b
+		}
+	}
+	return size
+}
+
+// MinRaise returns the smallest total a player may raise to in a no limit
+// game: the last full raise this round or the big blind, whichever is bigger,
+// on top of the current bet, but no more than the actor's stack. An all in
+// raise for less than that does not change the size of the next raise.
+func (this *Game) MinRaise() float64 {
+	max := this.maxBet()
+	min := this.Spent(this.Actor) - this.Bets[this.Round][this.Actor] + max + this.raiseBy()
+	if stack := this.stackOf(this.Actor); min > stack {
+		return stack
+	}
+	return min
+}
+
+// MaxRaise returns the largest total a player may raise to in a no limit
+// game, which is all of the actor's stack.
+func (this *Game) MaxRaise() float64 {
+	return this.stackOf(this.Actor)
+}
+
+// Spent returns the chips put in the pot by the player in seat this hand.
+func (this *Game) Spent(seat int) float64 {
+	var sum float64
+	for _, bets := range this.Bets {
+		sum += bets[seat]
+	}
+	return sum
+}
+
+func (this *Game) stackOf(seat int) float64 {
+	return float64(this.Stack(seat))
+}
+
+// allIn reports whether the player in seat has put all of their chips in the
+// pot. Players in limit games are never all in.
+func (this *Game) allIn(seat int) bool {
+	return !this.Limit() && this.Spent(seat) >= this.stackOf(seat)
+}
+
+// canAct reports whether the player in seat may still take an action.
+func (this *Game) canAct(seat int) bool {
+	return this.Actions[seat] != 'f' && !this.allIn(seat)
 }
 
 func (this *Game) Pot() float64 {
@@ -140,37 +298,107 @@ func (this *Game) Pot() float64 {
 	return sum
 }
 
-// Is there anyone in the hand who has not acted yet? If not, then does everyone
-// who has not folded have the same amount in the pot?
-// Pre-condition: two players have not folded.
-func (this *Game) evenBets() bool {
-	var j int
-	var b0 float64
+// Payoffs returns the chips each player won or lost in a finished hand. hands
+// holds the hole cards of each seat, which are only needed for the players
+// who reached the showdown. The pot is split between the best hands, with a
+// side pot for each player who went all in for less than the others.
+func (this *Game) Payoffs(hands [][]string) []float64 {
+	showdown := this.NumActive() > 1
+	spent := make([]float64, len(this.Actions))
+	ranks := make([]int32, len(this.Actions))
 	for i, a := range this.Actions {
-		if a == 0 {
-			return false
+		spent[i] = this.Spent(i)
+		if a != 'f' && showdown {
+			hand := append(append([]string(nil), hands[i]...), this.Board...)
+			ranks[i] = cards.Rank(hand)
+		}
+	}
+	payoffs := make([]float64, len(spent))
+	for i := range payoffs {
+		payoffs[i] = -spent[i]
+	}
+	// Award the pot in layers up to the chips put in by each player who has
+	// not folded, from the smallest to the biggest.
+	var level float64
+	for {
+		next := -1.0
+		for i, a := range this.Actions {
+			if a != 'f' && spent[i] > level && (next < 0 || spent[i] < next) {
+				next = spent[i]
+			}
 		}
-		if a != 'f' {
-			b0 = this.Bets[this.Round][i]
-			j = i
-			break
+		if next < 0 {
+			return payoffs
 		}
+		var pot float64
+		for _, chips := range spent {
+			pot += math.Min(chips, next) - math.Min(chips, level)
+		}
+		var winners []int
+		for i, a := range this.Actions {
+			if a == 'f' || spent[i] < next {
+				continue
+			}
+			if len(winners) > 0 && ranks[i] > ranks[winners[0]] {
+				winners = winners[:0]
+			}
+			if len(winners) == 0 || ranks[i] == ranks[winners[0]] {
+				winners = append(winners, i)
+			}
+		}
+		for _, i := range winners {
+			payoffs[i] += pot / float64(len(winners))
+		}
+		level = next
 	}
-	for i, a := range this.Actions[j+1:] {
-		if a == 0 || (a != 'f' && this.Bets[this.Round][i+j+1] != b0) {
+}
+
+// Is there anyone in the hand who can act and has not acted yet or has not
+// called the biggest bet? A lone player who can act does not need to act
+// unless there is a bet to call.
+// Pre-condition: two players have not folded.
+func (this *Game) evenBets() bool {
+	max := this.maxBet()
+	var n int
+	for i := range this.Actions {
+		if this.canAct(i) {
+			if this.Bets[this.Round][i] != max {
+				return false
+			}
+			n++
+		}
+	}
+	if n < 2 {
+		return true
+	}
+	for i, a := range this.Actions {
+		if this.canAct(i) && a == 0 {
 			return false
 		}
 	}
 	return true
 }
 
+// nextActor returns the first seat from seat onwards of a player who can act.
+func (this *Game) nextActor(seat int) int {
+	for i := range this.Actions {
+		j := (seat + i) % len(this.Actions)
+		if this.canAct(j) {
+			return j
+		}
+	}
+	return -1
+}
+
 func (this *Game) Update(event interface{}) {
 	this.Event = event
+	this.LastActor = -1
 	switch e := event.(type) {
 	case *diff.Players:
 		this.Actor = -1
 		this.Players = e
 		this.Round = -1
+		this.LastRaise = 0
 		for _, bets := range this.Bets {
 			for i := range bets {
 				bets[i] = 0
@@ -190,67 +418,116 @@ func (this *Game) Update(event interface{}) {
 		cards := splitCards(string(e))
 		this.Round++
 		this.Raises = 0
-		switch this.Round {
-		case PreFlop:
-			this.Actor = this.firstPlayer[this.Round] - 1
+		this.LastRaise = 0
+		switch {
+		case this.Round == PreFlop:
+			this.Actor = this.FirstPlayer(this.Round)
+			this.setHands(string(e))
 			this.Holes = cards
-		case Flop, Turn, River:
-			this.Actor = this.firstPlayer[this.Round] - 1
+			if this.Players != nil && this.Viewer >= 0 && this.Viewer < len(this.Hands) {
+				this.Holes = this.Hands[this.Viewer]
+			}
+		case this.Round < this.NumRounds():
+			this.Actor = this.FirstPlayer(this.Round)
 			this.Board = append(this.Board, cards...)
-		case Showdown:
+		default:
 			this.Actor = -1
+			this.setHands(string(e))
 			this.Holes = cards
 		}
+		// The first player may have already folded or gone all in.
+		if this.Actor != -1 {
+			if this.NumActive() < 2 || this.evenBets() {
+				this.Actor = -1
+			} else {
+				this.Actor = this.nextActor(this.Actor)
+			}
+		}
 	case diff.Action:
 		action := string(e)
+		this.LastActor = this.Actor
 		this.Actions[this.Actor] = action[0]
-		switch action {
-		case "c":
+		switch action[0] {
+		case 'c':
 			this.Bets[this.Round][this.Actor] += this.CallAmt()
-		case "r":
+		case 'r':
 			this.Raises++
-			this.Bets[this.Round][this.Actor] += this.RaiseAmt()
+			max, by := this.maxBet(), this.raiseBy()
+			// A no limit raise gives the total to raise to, e.g. r250.
+			if to, err := strconv.ParseFloat(action[1:], 64); err == nil && !this.Limit() {
+				this.Bets[this.Round][this.Actor] += to - this.Spent(this.Actor)
+			} else {
+				this.Bets[this.Round][this.Actor] += this.RaiseAmt()
+			}
+			// A short all in is not a full raise.
+			if size := this.Bets[this.Round][this.Actor] - max; size >= by {
+				this.LastRaise = size
+			}
 		}
-		if this.NumActive() < 2  || this.evenBets() {
+		if this.NumActive() < 2 || this.evenBets() {
 			this.Actor = -1
 			return
 		}
-		i := this.Actor
-		for {
-			i = (i + 1) % len(this.Actions)
-			if this.Actions[i] != 'f' {
-				this.Actor = i
-				return
-			}
-		}
+		this.Actor = this.nextActor(this.Actor + 1)
 	default:
 		panic("game: Invalid event passed to Update")
 	}
 }
 
-// Start playing a game.
-//	rules -- a String naming the game to play
-//	p     -- an object that implements the Player interface.
-//	host  -- the InetAddress of the dealer passed as a String
-//	port  -- the port the dealer is listening on for the client passed as a String
-func Play(rules string, p Player, host, port string) {
-	addr := net.JoinHostPort(host, port)
-	fmt.Printf("Connecting to dealer at %s to play %s...\n", addr, rules)
-	in, out, err := diff.NewACPC(addr)
+// Play connects to an ACPC dealer at host and port and plays a match of the
+// game named rules, as described by ChooseRules, with p. It returns the error
+// that ended the match early, if there was one.
+func Play(rules string, p Player, host, port string) error {
+	r, err := ChooseRules(rules)
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
-	game, err := NewGame(rules)
+	engine, err := diff.DialACPC(context.Background(), net.JoinHostPort(host, port), diff.ACPCOptions{})
 	if err != nil {
-		log.Fatalln(err)
-	}
-	for event := range in {
-		game.Update(event)
-		if game.Actor == game.Viewer {
-			out <- p.Play(game)
-		} else {
-			p.Observe(game)
+		return err
+	}
+	return PlayWith(engine, r, p)
+}
+
+// PlayWith plays a match with rules r through engine, passing its events to
+// p and p's actions back, until the match is over. If p is a HandStarter,
+// HandEnder, or MatchEnder, it is also told when each hand starts and ends and
+// when the match is over. A hand whose Players event gives its record is
+// played with r changed to fit it by HandRules. PlayWith closes engine,
+// and returns the error that ended the match early, if there was one.
+func PlayWith(engine diff.Engine, r *Rules, p Player) error {
+	return playWith(engine, r, p, newClock(TimeLimits{}))
+}
+
+func playWith(engine diff.Engine, r *Rules, p Player, clock *clock) error {
+	defer engine.Close()
+	game := NewGameRules(r)
+	var playing bool
+	for event := range engine.Events() {
+		if e, ok := event.(*diff.Players); ok && e.Hand != nil {
+			game = NewGameRules(HandRules(r, e.Hand))
+		}
+		if action, ok := game.dispatch(p, event, &playing, clock); ok {
+			select {
+			case engine.Actions() <- action:
+			case <-engine.Done():
+			}
 		}
 	}
-	fmt.Println("GAME OVER")
+	clock.finish()
+	err := <-engine.Errors()
+	if e, ok := p.(MatchEnder); ok {
+		e.MatchEnded(err)
+	}
+	return err
+}
+
+// Replay replays the hands of a hand history to p as if p were the player
+// called name, through a diff.Replay engine. p is asked to play at its turns
+// as usual, but the action from the history is always the one taken. Each hand
+// is played with r changed to fit the hand by HandRules. Hands without the
+// player are skipped. The player's optional callbacks are called as in
+// PlayWith.
+func Replay(r *Rules, log diff.HandReader, name string, p Player) error {
+	return PlayWith(diff.NewReplay(log, name), r, p)
 }
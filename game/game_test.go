@@ -0,0 +1,395 @@
+package game
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"poker/game/diff"
+)
+
+func ExampleRules_Deck() {
+	r, _ := ChooseRules("leduc")
+	fmt.Println(r.Deck())
+	// Output: [2c 2d 3c 3d 4c 4d]
+}
+
+func play(g *Game, events ...interface{}) {
+	for _, e := range events {
+		g.Update(e)
+	}
+}
+
+func TestKuhn(test *testing.T) {
+	g, err := NewGame("kuhn")
+	if err != nil {
+		test.Fatal(err)
+	}
+	play(g, &diff.Players{Viewer: 0}, diff.Cards("4c|"))
+	if g.Actor != 0 || g.LegalActions() != "cr" {
+		test.Fatalf("Expected player 0 to check or bet, but got\n%v", g)
+	}
+	play(g, diff.Action("c"), diff.Action("r"))
+	if g.Actor != 0 || g.LastActor != 1 || g.LegalActions() != "cf" {
+		test.Fatalf("Expected player 0 to call or fold after player 1 bet, but got\n%v", g)
+	}
+	play(g, diff.Action("c"), diff.Cards("4c|2c"))
+	if g.Round != g.NumRounds() || g.Pot() != 4 || g.LastActor != -1 {
+		test.Fatalf("Expected a showdown with a pot of 4, but got\n%v", g)
+	}
+}
+
+func TestLeduc(test *testing.T) {
+	g, err := NewGame("leduc")
+	if err != nil {
+		test.Fatal(err)
+	}
+	play(g, &diff.Players{Viewer: 1}, diff.Cards("|3d"),
+		diff.Action("r"), diff.Action("r"), diff.Action("c"))
+	if g.Actor != -1 || g.Pot() != 10 {
+		test.Fatalf("Expected the first round to be over, but got\n%v", g)
+	}
+	play(g, diff.Cards("/3c"))
+	if g.Round != Flop || len(g.Board) != 1 || g.RaiseAmt() != 4 {
+		test.Fatalf("Expected the second round to start, but got\n%v", g)
+	}
+	play(g, diff.Action("c"), diff.Action("r"), diff.Action("f"))
+	if g.Actor != -1 || g.NumActive() != 1 {
+		test.Fatalf("Expected the hand to be over, but got\n%v", g)
+	}
+}
+
+func TestNoLimit(test *testing.T) {
+	g, err := NewGame("2p-nl")
+	if err != nil {
+		test.Fatal(err)
+	}
+	play(g, &diff.Players{Viewer: 0}, diff.Cards("AsAd|"))
+	if g.Actor != 1 || g.MinRaise() != 200 || g.MaxRaise() != 20000 {
+		test.Fatalf("Expected player 1 to raise between 200 and 20000, but got\n%v", g)
+	}
+	play(g, diff.Action("r300"))
+	if g.MinRaise() != 500 || g.CallAmt() != 200 {
+		test.Fatalf("Expected player 0 to call 200 or raise to 500, but got\n%v", g)
+	}
+	play(g, diff.Action("r20000"), diff.Action("c"))
+	if g.Actor != -1 || g.Pot() != 40000 {
+		test.Fatalf("Expected both players to be all in, but got\n%v", g)
+	}
+	play(g, diff.Cards("/2c7d9h"))
+	if g.Actor != -1 {
+		test.Fatalf("Expected no one to be able to act after going all in\n")
+	}
+	play(g, diff.Cards("/Jc"), diff.Cards("/Qh"))
+	payoffs := g.Payoffs([][]string{{"As", "Ad"}, {"Kc", "Kd"}})
+	if payoffs[0] != 20000 || payoffs[1] != -20000 {
+		test.Fatalf("Expected aces to win 20000, but got %v\n", payoffs)
+	}
+}
+
+func TestShortAllIn(test *testing.T) {
+	def := strings.NewReplacer("numPlayers = 2", "numPlayers = 3", "stack = 20000 20000",
+		"stack = 250 20000 20000", "blind = 100 50", "blind = 50 100 0",
+		"firstPlayer = 2 1 1 1", "firstPlayer = 3 1 1 1").Replace(holdemNoLimit)
+	r, err := ReadRules(strings.NewReader(def))
+	if err != nil {
+		test.Fatal(err)
+	}
+	g := NewGameRules(r)
+	play(g, &diff.Players{Viewer: 1}, diff.Cards("|AsAd|"), diff.Action("r300"), diff.Action("c"))
+	if g.Actor != 1 || g.Spent(0) != 250 || g.MinRaise() != 500 {
+		test.Fatalf("Expected player 1 to raise to at least 500 after a short call, but got\n%v", g)
+	}
+	play(g, diff.Action("r1000"), diff.Action("c"))
+	play(g, diff.Cards("/2c7d9h"), diff.Action("r1300"))
+	if g.MinRaise() != 1600 {
+		test.Fatalf("Expected a raise to at least 1600 after a bet of 300, but got %g\n", g.MinRaise())
+	}
+}
+
+func TestThreePlayer(test *testing.T) {
+	g, err := NewGame("3p-l")
+	if err != nil {
+		test.Fatal(err)
+	}
+	play(g, &diff.Players{Viewer: 2}, diff.Cards("||4c8h"),
+		diff.Action("r"), diff.Action("c"), diff.Action("f"), diff.Cards("2c7d9h"))
+	if g.Round != Flop || g.NumActive() != 2 || !reflect.DeepEqual(g.Holes, []string{"4c", "8h"}) {
+		test.Fatalf("Expected the flop with two players, but got\n%v", g)
+	}
+	play(g, diff.Action("c"), diff.Action("c"), diff.Cards("Jc"),
+		diff.Action("c"), diff.Action("c"), diff.Cards("Qh"),
+		diff.Action("c"), diff.Action("c"), diff.Cards("5d5c||4c8h"))
+	hands := [][]string{{"5d", "5c"}, nil, {"4c", "8h"}}
+	if g.Round != g.NumRounds() || !reflect.DeepEqual(g.Hands, hands) {
+		test.Fatalf("Expected the hands %v at the showdown, but got %v\n", hands, g.Hands)
+	}
+}
+
+// counter counts the times it is asked to play and the hands it sees end.
+type counter struct {
+	plays, hands int
+}
+
+func (this *counter) Play(g *Game) string {
+	this.plays++
+	return "c"
+}
+
+func (this *counter) Observe(g *Game) {
+	if g.Actor == -1 && g.Round == g.NumRounds() || g.NumActive() == 1 {
+		this.hands++
+	}
+}
+
+func TestReplay(test *testing.T) {
+	r, _ := ChooseRules("2p-l")
+	f, err := os.Open("diff/test-game.log")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer f.Close()
+	log := diff.NewACPCLog(f)
+	g := NewGameRules(r)
+	for {
+		h, err := log.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			test.Fatal(err)
+		}
+		play(g, h.Events(0)...)
+		if payoffs := g.Payoffs(h.Holes); !reflect.DeepEqual(payoffs, h.Results) {
+			test.Fatalf("Expected %v, but got %v in %v\n", h.Results, payoffs, h)
+		}
+	}
+
+	f.Seek(0, 0)
+	p := new(counter)
+	if err := Replay(r, diff.NewACPCLog(f), "Alice", p); err != nil {
+		test.Fatal(err)
+	}
+	if p.plays == 0 || p.hands != 1000 {
+		test.Fatalf("Expected to see 1000 hands end, but saw %d\n", p.hands)
+	}
+}
+
+// tracker counts the hands it is told about and adds up its winnings.
+type tracker struct {
+	counter
+	started, ended, matches int
+	won                     float64
+}
+
+func (this *tracker) HandStarted(g *Game) {
+	this.started++
+}
+
+func (this *tracker) HandEnded(g *Game, results []float64) {
+	this.ended++
+	this.won += results[g.Viewer]
+}
+
+func (this *tracker) MatchEnded(err error) {
+	this.matches++
+}
+
+func TestCallbacks(test *testing.T) {
+	r, _ := ChooseRules("2p-l")
+	f, err := os.Open("diff/test-game.log")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer f.Close()
+	p := new(tracker)
+	if err := Replay(r, diff.NewACPCLog(f), "Alice", LogHands(p, diff.NewACPCLogWriter(io.Discard), "Alice")); err != nil {
+		test.Fatal(err)
+	}
+	if p.started != 1000 || p.ended != 1000 || p.matches != 1 {
+		test.Fatalf("Expected 1000 hands and 1 match, but got %d started, %d ended, and %d matches\n",
+			p.started, p.ended, p.matches)
+	}
+	if p.won != -24980 {
+		test.Fatalf("Expected Alice to win -24980, but got %g\n", p.won)
+	}
+}
+
+func TestLogHands(test *testing.T) {
+	r, _ := ChooseRules("2p-l")
+	f, err := os.Open("diff/test-game.log")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer f.Close()
+	buf := new(bytes.Buffer)
+	out := diff.NewACPCLogWriter(buf)
+	if err := Replay(r, diff.NewACPCLog(f), "Alice", LogHands(new(counter), out, "Alice")); err != nil {
+		test.Fatal(err)
+	}
+	out.WriteScore()
+	if !strings.HasSuffix(buf.String(), "\nSCORE:-24980|24980:Alice|Hobbes\n") {
+		test.Fatalf("Expected the score to match the original log\n")
+	}
+	f.Seek(0, 0)
+	original, logged := diff.NewACPCLog(f), diff.NewACPCLog(buf)
+	for {
+		h, err := original.Next()
+		if err == io.EOF {
+			break
+		}
+		h2, err := logged.Next()
+		if err != nil {
+			test.Fatal(err)
+		}
+		// Only Alice's cards are seen when a player folds.
+		h2.Holes = h.Holes
+		if !reflect.DeepEqual(h, h2) {
+			test.Fatalf("Expected\n%v\nbut logged\n%v\n", h, h2)
+		}
+	}
+}
+
+func TestHandRules(test *testing.T) {
+	r, _ := ChooseRules("2p-nl")
+	f, err := os.Open("diff/test-pokerstars.txt")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer f.Close()
+	log := diff.NewPokerStarsLog(f)
+	// The ante of each seat in the hands with antes, which are not bets, so
+	// they are not in the game, but are in the results.
+	antes := map[int]float64{212345678903: 1}
+	for {
+		h, err := log.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			test.Fatal(err)
+		}
+		g := NewGameRules(HandRules(r, h))
+		play(g, h.Events(h.Seat("Alice"))...)
+		payoffs := g.Payoffs(h.Holes)
+		if ante := antes[h.Num]; ante > 0 {
+			for i, chips := range payoffs {
+				payoffs[i] -= ante
+				if chips > 0 {
+					payoffs[i] += ante * float64(len(payoffs))
+				}
+			}
+		}
+		if !reflect.DeepEqual(payoffs, h.Results) {
+			test.Fatalf("Expected %v, but got %v in %v\n", h.Results, payoffs, h)
+		}
+	}
+}
+
+// engine sends the events of some hands seen by seat 0 and collects the
+// actions sent back.
+type engine struct {
+	events  chan interface{}
+	actions chan string
+	errs    chan error
+	done    chan struct{}
+	got     []string
+}
+
+func newEngine(hands ...*diff.Hand) *engine {
+	this := &engine{
+		events:  make(chan interface{}),
+		actions: make(chan string),
+		errs:    make(chan error),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		for _, h := range hands {
+			for _, e := range h.Events(0) {
+				this.events <- e
+			}
+		}
+		close(this.events)
+		close(this.errs)
+	}()
+	return this
+}
+
+func (this *engine) Events() <-chan interface{} { return this.events }
+func (this *engine) Actions() chan<- string     { return this.actions }
+func (this *engine) Errors() <-chan error       { return this.errs }
+func (this *engine) Done() <-chan struct{}      { return this.done }
+func (this *engine) Close() error               { return nil }
+
+// slow folds, but takes too long to do it.
+type slow struct {
+	delay    time.Duration
+	plays    int // The times asked to play with a deadline.
+	observes int
+}
+
+func (this *slow) Play(g *Game) string {
+	if !g.Deadline.IsZero() {
+		this.plays++
+	}
+	time.Sleep(this.delay)
+	return "f"
+}
+
+func (this *slow) Observe(_ *Game) {
+	this.observes++
+}
+
+func TestPlayTimed(test *testing.T) {
+	r, _ := ChooseRules("2p-l")
+	h, _ := diff.ParseHand("STATE:0:crrc/cc/cc/cc:AsAd|KcKd/2c7d9h/Jc/Qh:60|-60:Alice|Bob")
+	e := newEngine(h)
+	got := make(chan []string)
+	go func() {
+		var actions []string
+		for a := range e.actions {
+			actions = append(actions, a)
+		}
+		got <- actions
+	}()
+	p := &slow{delay: 40 * time.Millisecond}
+	err := PlayTimed(e, r, p, TimeLimits{Action: 10 * time.Millisecond, Hand: 25 * time.Millisecond})
+	close(e.actions)
+	if err != nil {
+		test.Fatal(err)
+	}
+	// Alice runs out of time on her first action, so her other turns are
+	// answered without her, and she is told of them once she is back.
+	actions := <-got
+	if !reflect.DeepEqual(actions, []string{"c", "c", "c", "c", "c"}) || p.plays != 1 {
+		test.Fatalf("Expected 5 calls with Alice asked once, but got %v with %d\n", actions, p.plays)
+	}
+	if n := len(h.Events(0)); p.plays+p.observes != n {
+		test.Fatalf("Expected Alice to see %d events, but she saw %d\n", n, p.plays+p.observes)
+	}
+}
+
+func TestClockLate(test *testing.T) {
+	g, _ := NewGame("2p-l")
+	g.Update(&diff.Players{Viewer: 1})
+	g.Update(diff.Cards("|AsAd"))
+	c := newClock(TimeLimits{Action: 10 * time.Millisecond, Match: time.Second})
+	p := &slow{delay: 100 * time.Millisecond}
+	if a := c.play(p, g); a != "c" || c.match != time.Second-10*time.Millisecond {
+		test.Fatalf("Expected a call and 990ms left, but got %q and %v\n", a, c.match)
+	}
+	start := time.Now()
+	if a := c.play(p, g); a != "c" || time.Since(start) > 50*time.Millisecond {
+		test.Fatalf("Expected a call at once without asking the late player, but got %q\n", a)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if c.late() || p.plays != 1 || p.observes != 1 {
+		test.Fatalf("Expected the player to be back and told of its missed turn\n")
+	}
+	if c.match > 950*time.Millisecond {
+		test.Fatalf("Expected the player to be charged for being late, but it has %v left\n", c.match)
+	}
+}
@@ -0,0 +1,250 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Limits on the size of a game, as in the ACPC dealer.
+const (
+	MaxPlayers    = 10
+	MaxRounds     = 4
+	MaxHoleCards  = 3
+	MaxBoardCards = 7
+	MaxSuits      = 4
+	MaxRanks      = 13
+)
+
+// LoadRules reads the rules of a game from an ACPC game definition file.
+func LoadRules(file string) (*Rules, error) {
+	fp, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	r, err := ReadRules(fp)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	r.descr = file
+	return r, nil
+}
+
+// ReadRules parses the rules of a game in the ACPC GAMEDEF format:
+//
+//	GAMEDEF
+//	limit
+//	numPlayers = 2
+//	numRounds = 4
+//	blind = 10 5
+//	raiseSize = 10 10 20 20
+//	firstPlayer = 2 1 1 1
+//	maxRaises = 3 4 4 4
+//	numSuits = 4
+//	numRanks = 13
+//	numHoleCards = 2
+//	numBoardCards = 0 3 1 1
+//	END GAMEDEF
+//
+// Keys are not case sensitive and lines starting with "#" are comments. As in
+// the ACPC dealer, missing blinds are 0, missing stacks are unlimited, and
+// missing raise caps allow any number of raises.
+func ReadRules(rd io.Reader) (*Rules, error) {
+	r := &Rules{limit: true}
+	vals := make(map[string][]int)
+	scanner := bufio.NewScanner(rd)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		key := strings.ToLower(line)
+		switch key {
+		case "gamedef":
+			continue
+		case "end gamedef":
+			return r, r.fill(vals)
+		case "limit":
+			r.limit = true
+			continue
+		case "nolimit":
+			r.limit = false
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: can't parse %q", n, line)
+		}
+		key = strings.ToLower(strings.TrimSpace(line[:i]))
+		fields := strings.Fields(line[i+1:])
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("line %d: %s has no values", n, key)
+		}
+		for _, f := range fields {
+			v, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad value for %s: %v", n, key, err)
+			}
+			vals[key] = append(vals[key], v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return r, r.fill(vals)
+}
+
+// fill sets the fields of the rules from the values read from a game
+// definition and checks that they describe a playable game.
+func (this *Rules) fill(vals map[string][]int) error {
+	single := func(key string, min, max int) (int, error) {
+		v := vals[key]
+		if len(v) != 1 {
+			return 0, fmt.Errorf("%s must have exactly one value", key)
+		}
+		if v[0] < min || v[0] > max {
+			return 0, fmt.Errorf("%s must be between %d and %d", key, min, max)
+		}
+		return v[0], nil
+	}
+	// padded returns the values of key padded with def up to n values.
+	padded := func(key string, n, def int) ([]int, error) {
+		v := vals[key]
+		if len(v) > n {
+			return nil, fmt.Errorf("%s has more than %d values", key, n)
+		}
+		for len(v) < n {
+			v = append(v, def)
+		}
+		return v, nil
+	}
+	var err error
+	for key := range vals {
+		switch key {
+		case "numplayers", "numrounds", "stack", "blind", "raisesize",
+			"firstplayer", "maxraises", "numsuits", "numranks",
+			"numholecards", "numboardcards":
+		default:
+			return fmt.Errorf("unknown key %s", key)
+		}
+	}
+	if this.numPlayers, err = single("numplayers", 2, MaxPlayers); err != nil {
+		return err
+	}
+	if this.numRounds, err = single("numrounds", 1, MaxRounds); err != nil {
+		return err
+	}
+	if this.numSuits, err = single("numsuits", 1, MaxSuits); err != nil {
+		return err
+	}
+	if this.numRanks, err = single("numranks", 1, MaxRanks); err != nil {
+		return err
+	}
+	if this.numHoleCards, err = single("numholecards", 1, MaxHoleCards); err != nil {
+		return err
+	}
+	blind, err := padded("blind", this.numPlayers, 0)
+	if err != nil {
+		return err
+	}
+	this.blind = make([]float64, len(blind))
+	for i, b := range blind {
+		this.blind[i] = float64(b)
+	}
+	if this.firstPlayer, err = padded("firstplayer", this.numRounds, 1); err != nil {
+		return err
+	}
+	for _, p := range this.firstPlayer {
+		if p < 1 || p > this.numPlayers {
+			return fmt.Errorf("firstPlayer must be between 1 and %d", this.numPlayers)
+		}
+	}
+	if this.maxRaises, err = padded("maxraises", this.numRounds, unlimitedRaises); err != nil {
+		return err
+	}
+	if this.numBoardCards, err = padded("numboardcards", this.numRounds, 0); err != nil {
+		return err
+	}
+	deal := this.numPlayers * this.numHoleCards
+	board := 0
+	for _, n := range this.numBoardCards {
+		board += n
+	}
+	if board > MaxBoardCards {
+		return fmt.Errorf("more than %d board cards", MaxBoardCards)
+	}
+	if deal+board > this.numSuits*this.numRanks {
+		return fmt.Errorf("not enough cards in the deck")
+	}
+	if this.limit {
+		// Limit games are played without stacks, so a stack line is ignored.
+		raiseSize := vals["raisesize"]
+		if len(raiseSize) != this.numRounds {
+			return fmt.Errorf("raiseSize must have a value for each round")
+		}
+		this.raiseSize = make([]float64, len(raiseSize))
+		for i, s := range raiseSize {
+			this.raiseSize[i] = float64(s)
+		}
+	} else {
+		if _, ok := vals["raisesize"]; ok {
+			return fmt.Errorf("a no limit game can not have a raiseSize")
+		}
+		if this.stack, err = padded("stack", this.numPlayers, 1<<31-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTo writes the rules to w in the ACPC GAMEDEF format.
+func (this *Rules) WriteTo(w io.Writer) (int64, error) {
+	buf := new(bytes.Buffer)
+	ints := func(key string, vals []int) {
+		buf.WriteString(key + " =")
+		for _, v := range vals {
+			fmt.Fprintf(buf, " %d", v)
+		}
+		buf.WriteString("\n")
+	}
+	floats := func(key string, vals []float64) {
+		buf.WriteString(key + " =")
+		for _, v := range vals {
+			fmt.Fprintf(buf, " %.0f", v)
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("GAMEDEF\n")
+	if this.limit {
+		buf.WriteString("limit\n")
+	} else {
+		buf.WriteString("nolimit\n")
+	}
+	fmt.Fprintf(buf, "numPlayers = %d\n", this.numPlayers)
+	fmt.Fprintf(buf, "numRounds = %d\n", this.numRounds)
+	if !this.limit {
+		ints("stack", this.stack)
+	}
+	floats("blind", this.blind)
+	if this.limit {
+		floats("raiseSize", this.raiseSize)
+	}
+	ints("firstPlayer", this.firstPlayer)
+	for _, m := range this.maxRaises {
+		if m != unlimitedRaises {
+			ints("maxRaises", this.maxRaises)
+			break
+		}
+	}
+	fmt.Fprintf(buf, "numSuits = %d\n", this.numSuits)
+	fmt.Fprintf(buf, "numRanks = %d\n", this.numRanks)
+	fmt.Fprintf(buf, "numHoleCards = %d\n", this.numHoleCards)
+	ints("numBoardCards", this.numBoardCards)
+	buf.WriteString("END GAMEDEF\n")
+	return buf.WriteTo(w)
+}
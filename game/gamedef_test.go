@@ -0,0 +1,104 @@
+package game
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const holdemNoLimit = `# Two player no limit Texas Hold'em.
+GAMEDEF
+nolimit
+numPlayers = 2
+numRounds = 4
+stack = 20000 20000
+blind = 100 50
+firstPlayer = 2 1 1 1
+numSuits = 4
+numRanks = 13
+numHoleCards = 2
+numBoardCards = 0 3 1 1
+END GAMEDEF
+`
+
+func ExampleRules_WriteTo() {
+	r, _ := ChooseRules("2p-l")
+	r.WriteTo(os.Stdout)
+	// Output:
+	// GAMEDEF
+	// limit
+	// numPlayers = 2
+	// numRounds = 4
+	// blind = 10 5
+	// raiseSize = 10 10 20 20
+	// firstPlayer = 2 1 1 1
+	// maxRaises = 3 4 4 4
+	// numSuits = 4
+	// numRanks = 13
+	// numHoleCards = 2
+	// numBoardCards = 0 3 1 1
+	// END GAMEDEF
+}
+
+func TestReadRules(test *testing.T) {
+	r, err := ReadRules(strings.NewReader(holdemNoLimit))
+	if err != nil {
+		test.Fatal(err)
+	}
+	if r.Limit() || r.NumPlayers() != 2 || r.Stack(1) != 20000 ||
+		r.Blind(0) != 100 || r.FirstPlayer(0) != 1 ||
+		r.MaxRaises(3) != unlimitedRaises || r.NumBoardCards(1) != 3 {
+		test.Fatalf("Parsed the wrong rules: %+v\n", r)
+	}
+	buf := new(bytes.Buffer)
+	if _, err := r.WriteTo(buf); err != nil {
+		test.Fatal(err)
+	}
+	r2, err := ReadRules(buf)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if !reflect.DeepEqual(r, r2) {
+		test.Fatalf("%+v changed to %+v after writing it\n", r, r2)
+	}
+	bad := strings.Replace(holdemNoLimit, "numHoleCards = 2", "numHoleCards = 30", 1)
+	if _, err := ReadRules(strings.NewReader(bad)); err == nil {
+		test.Fatalf("Expected an error reading\n%s", bad)
+	}
+	limit := strings.Replace(holdemNoLimit, "nolimit", "limit\nraiseSize = 100 100 200 200", 1)
+	r, err = ReadRules(strings.NewReader(limit))
+	if err != nil {
+		test.Fatal(err)
+	}
+	if !r.Limit() || r.Stack(0) != 0 || r.RaiseSize(2) != 200 {
+		test.Fatalf("Parsed the wrong rules: %+v\n", r)
+	}
+}
+
+func TestRegisterRules(test *testing.T) {
+	r, err := ReadRules(strings.NewReader(holdemNoLimit))
+	if err != nil {
+		test.Fatal(err)
+	}
+	RegisterRules("test-nl", r)
+	defer func() {
+		// Remove the rules, so that the test may run again.
+		registryMu.Lock()
+		delete(registry, "test-nl")
+		registryMu.Unlock()
+	}()
+	g, err := NewGame("test-nl")
+	if err != nil {
+		test.Fatal(err)
+	}
+	if g.Description() != "test-nl" || r.Description() == "test-nl" {
+		test.Fatalf("Expected only the registered copy to be named test-nl\n")
+	}
+	blinds := g.Blinds()
+	blinds[0] = 0
+	if g.Blind(0) != 100 {
+		test.Fatalf("Changing a copy of the blinds changed the rules\n")
+	}
+}
@@ -0,0 +1,90 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"poker/game/diff"
+)
+
+// NewJSONHand returns the JSON record of h, which was played with rules r. The
+// hand is replayed to work out which seat took each action and its amounts,
+// which depend on the order of play and the raise sizes of the rules. That
+// needs a Game, so the encoder is here rather than with JSONHand in package
+// diff, which package game imports.
+func NewJSONHand(r *Rules, h *diff.Hand) (*diff.JSONHand, error) {
+	r = HandRules(r, h)
+	n := r.NumPlayers()
+	if len(h.Players) != n || len(h.Results) != n {
+		return nil, fmt.Errorf("game: hand %d does not have %d players", h.Num, n)
+	}
+	j := &diff.JSONHand{
+		Rules:   r.Description(),
+		Limit:   r.Limit(),
+		Num:     h.Num,
+		Players: h.Players,
+		Blinds:  r.Blinds(),
+		Holes:   make([][]string, n),
+		Results: h.Results,
+	}
+	if !r.Limit() {
+		for _, s := range r.Stacks() {
+			j.Stacks = append(j.Stacks, float64(s))
+		}
+	}
+	for i := range j.Holes {
+		j.Holes[i] = append([]string{}, h.Holes[i]...)
+	}
+	for i := range h.Actions {
+		j.Rounds = append(j.Rounds, diff.JSONRound{
+			Board:   append([]string{}, h.Board[i]...),
+			Actions: []diff.JSONAction{},
+		})
+	}
+	g := NewGameRules(r)
+	for _, event := range h.Events(0) {
+		a, ok := event.(diff.Action)
+		if !ok {
+			g.Update(event)
+			continue
+		}
+		round, seat, high, before := g.Round, g.Actor, g.maxBet(), g.Spent(g.Actor)
+		g.Update(event)
+		ja := diff.JSONAction{Seat: seat, Amount: g.Spent(seat) - before, Total: g.Spent(seat)}
+		switch {
+		case a.Kind() == 'f':
+			ja.Action = "fold"
+		case a.Kind() == 'c' && ja.Amount == 0:
+			ja.Action = "check"
+		case a.Kind() == 'c':
+			ja.Action = "call"
+		case high == 0:
+			ja.Action = "bet"
+		default:
+			ja.Action = "raise"
+		}
+		j.Rounds[round].Actions = append(j.Rounds[round].Actions, ja)
+	}
+	return j, nil
+}
+
+// A JSONWriter writes hands as JSON hands, one per line.
+type JSONWriter struct {
+	enc   *json.Encoder
+	rules *Rules
+}
+
+// NewJSONWriter returns a writer of hands played with rules r to w.
+func NewJSONWriter(w io.Writer, r *Rules) *JSONWriter {
+	return &JSONWriter{enc: json.NewEncoder(w), rules: r}
+}
+
+// WriteHand writes the JSON record of a hand on one line.
+func (this *JSONWriter) WriteHand(h *diff.Hand) error {
+	j, err := NewJSONHand(this.rules, h)
+	if err != nil {
+		return err
+	}
+	return this.enc.Encode(j)
+}
@@ -0,0 +1,71 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"poker/game/diff"
+)
+
+func ExampleNewJSONHand() {
+	r, _ := ChooseRules("2p-nl")
+	h, _ := diff.ParseHand("STATE:7:r300c/cr900f:AsAd|KcKd/2c7d9h:-300|300:Alice|Bob")
+	j, err := NewJSONHand(r, h)
+	if err != nil {
+		panic(err)
+	}
+	data, _ := json.Marshal(j.Rounds)
+	fmt.Println(string(data))
+	// Output:
+	// [{"board":[],"actions":[{"seat":1,"action":"raise","amount":250,"total":300},{"seat":0,"action":"call","amount":200,"total":300}]},{"board":["2c","7d","9h"],"actions":[{"seat":0,"action":"check","amount":0,"total":300},{"seat":1,"action":"bet","amount":600,"total":900},{"seat":0,"action":"fold","amount":0,"total":300}]}]
+}
+
+func TestJSONWriter(test *testing.T) {
+	r, _ := ChooseRules("2p-l")
+	f, err := os.Open("diff/test-game.log")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer f.Close()
+	log := diff.NewACPCLog(f)
+	buf := new(bytes.Buffer)
+	w := NewJSONWriter(buf, r)
+	var hands []*diff.Hand
+	for {
+		h, err := log.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			test.Fatal(err)
+		}
+		if err := w.WriteHand(h); err != nil {
+			test.Fatal(err)
+		}
+		hands = append(hands, h)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("\n")); n != len(hands) {
+		test.Fatalf("Expected %d lines, but got %d\n", len(hands), n)
+	}
+	jsonLog := diff.NewJSONLog(buf)
+	for _, h := range hands {
+		h2, err := jsonLog.Next()
+		if err != nil {
+			test.Fatal(err)
+		}
+		if !reflect.DeepEqual(h2.Blinds, []float64{10, 5}) {
+			test.Fatalf("Expected the blinds of %s, but got %v\n", r.Description(), h2.Blinds)
+		}
+		h2.Blinds = nil
+		if !reflect.DeepEqual(h, h2) {
+			test.Fatalf("Expected\n%v\nbut read back\n%v\n", h, h2)
+		}
+	}
+	if _, err := jsonLog.Next(); err != io.EOF {
+		test.Fatalf("Expected the end of the log, but got %v\n", err)
+	}
+}
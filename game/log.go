@@ -0,0 +1,98 @@
+package game
+
+import (
+	"fmt"
+
+	"poker/game/diff"
+)
+
+// A logger is a Player that records the hands it sees as they are played.
+type logger struct {
+	Player
+	log  diff.HandWriter
+	name string
+	num  int
+	hand *diff.Hand // The hand being played, or nil between hands.
+}
+
+// LogHands returns a Player that plays like p and writes each hand it plays
+// to log. p is called name in the log. If the engine does not give the names
+// of the other players, they are called opponent1, opponent2, and so on, by
+// their position to the left of p. Only the cards p saw are logged. Errors
+// writing the log are ignored, so that they do not stop the match.
+func LogHands(p Player, log diff.HandWriter, name string) Player {
+	return &logger{Player: p, log: log, name: name}
+}
+
+func (this *logger) Play(g *Game) string {
+	this.record(g)
+	return this.Player.Play(g)
+}
+
+func (this *logger) Observe(g *Game) {
+	this.record(g)
+	this.Player.Observe(g)
+}
+
+func (this *logger) HandStarted(g *Game) {
+	if s, ok := this.Player.(HandStarter); ok {
+		s.HandStarted(g)
+	}
+}
+
+func (this *logger) HandEnded(g *Game, results []float64) {
+	if e, ok := this.Player.(HandEnder); ok {
+		e.HandEnded(g, results)
+	}
+}
+
+func (this *logger) MatchEnded(err error) {
+	if e, ok := this.Player.(MatchEnder); ok {
+		e.MatchEnded(err)
+	}
+}
+
+// record adds the latest event of g to the hand, and writes the hand once it
+// is over.
+func (this *logger) record(g *Game) {
+	switch e := g.Event.(type) {
+	case *diff.Players:
+		n := len(g.Actions)
+		names := make([]string, n)
+		for i := range names {
+			switch {
+			case i < len(e.Names):
+				names[i] = e.Names[i]
+			case i == e.Viewer:
+				names[i] = this.name
+			default:
+				names[i] = fmt.Sprintf("opponent%d", (i-e.Viewer+n)%n)
+			}
+		}
+		this.hand = &diff.Hand{Num: this.num, Players: names, Actions: [][]diff.Action{nil}}
+		this.num++
+	case diff.Cards:
+		if this.hand != nil && g.Round > PreFlop && g.Round < g.NumRounds() {
+			this.hand.Actions = append(this.hand.Actions, nil)
+		}
+	case diff.Action:
+		if this.hand != nil {
+			r := len(this.hand.Actions) - 1
+			this.hand.Actions[r] = append(this.hand.Actions[r], e)
+		}
+	}
+	if this.hand == nil || !g.handOver() {
+		return
+	}
+	h := this.hand
+	h.Holes = g.Hands
+	h.Board = make([][]string, len(h.Actions))
+	dealt := 0
+	for r := 1; r < len(h.Board); r++ {
+		h.Board[r] = g.Board[dealt : dealt+g.NumBoardCards(r)]
+		dealt += g.NumBoardCards(r)
+	}
+	h.Results = g.Payoffs(g.Hands)
+	this.log.WriteHand(h)
+	this.hand = nil
+}
@@ -0,0 +1,219 @@
+package game
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"poker/game/diff"
+)
+
+// The names PokerStars gives the rounds after the first.
+var streets = []string{"Flop", "Turn", "River"}
+
+// A PokerStarsWriter writes hands as a PokerStars hand history, which most
+// hand replayers can show. Amounts are written in chips, as in a tournament.
+// Each hand is replayed with the rules to work out the amount of each bet.
+// Limit games have no stacks, so each seat is given 1000 big blinds.
+type PokerStarsWriter struct {
+	Table string    // The name of the table, the rules' description if "".
+	Time  time.Time // When each hand was played, the time it is written if zero.
+	w     io.Writer
+	rules *Rules
+}
+
+// NewPokerStarsWriter returns a writer of hands played with rules r to w.
+func NewPokerStarsWriter(w io.Writer, r *Rules) *PokerStarsWriter {
+	return &PokerStarsWriter{w: w, rules: r}
+}
+
+func chips(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// WriteHand writes a hand followed by a blank line.
+func (this *PokerStarsWriter) WriteHand(h *diff.Hand) error {
+	r := HandRules(this.rules, h)
+	n := r.NumPlayers()
+	if len(h.Players) != n || len(h.Results) != n {
+		return fmt.Errorf("game: hand %d does not have %d players", h.Num, n)
+	}
+	var buf bytes.Buffer
+	kind := "No Limit"
+	stakes := fmt.Sprintf("%s/%s", chips(r.smallBlind()), chips(r.bigBlind()))
+	if r.Limit() {
+		kind = "Limit"
+		stakes = fmt.Sprintf("%s/%s", chips(r.RaiseSize(0)), chips(r.RaiseSize(r.NumRounds()-1)))
+	}
+	when := this.Time
+	if when.IsZero() {
+		when = time.Now()
+	}
+	table := this.Table
+	if table == "" {
+		table = r.Description()
+	}
+	fmt.Fprintf(&buf, "PokerStars Hand #%d:  Hold'em %s (%s) - %s ET\n", h.Num, kind,
+		stakes, when.Format("2006/01/02 15:04:05"))
+	// Seat i+1 at the table is seat i in the hand, so the button is seat n.
+	fmt.Fprintf(&buf, "Table '%s' %d-max Seat #%d is the button\n", table, n, n)
+	for i, name := range h.Players {
+		stack := float64(r.Stack(i))
+		if stack == 0 {
+			stack = 1000 * r.bigBlind()
+		}
+		fmt.Fprintf(&buf, "Seat %d: %s (%s in chips)\n", i+1, name, chips(stack))
+	}
+	// The small blinds are posted before the big blind.
+	for _, big := range []bool{false, true} {
+		for i, name := range h.Players {
+			if b := r.Blind(i); b > 0 && (b == r.bigBlind()) == big {
+				fmt.Fprintf(&buf, "%s: posts %s blind %s\n", name, blindName(big), chips(b))
+			}
+		}
+	}
+
+	g := NewGameRules(r)
+	folded := make([]int, n) // The round in which each seat folded, or -1.
+	for _, event := range h.Events(0) {
+		var before []float64
+		var actor int
+		var high float64
+		switch e := event.(type) {
+		case diff.Cards:
+			switch round := g.Round + 1; {
+			case round == PreFlop:
+				buf.WriteString("*** HOLE CARDS ***\n")
+				for i, hole := range h.Holes {
+					if len(hole) > 0 {
+						fmt.Fprintf(&buf, "Dealt to %s [%s]\n", h.Players[i], strings.Join(hole, " "))
+					}
+				}
+			case round < r.NumRounds():
+				fmt.Fprintf(&buf, "*** %s *** ", strings.ToUpper(street(round)))
+				if len(g.Board) > 0 {
+					fmt.Fprintf(&buf, "[%s] ", strings.Join(g.Board, " "))
+				}
+				fmt.Fprintf(&buf, "[%s]\n", strings.Join(h.Board[round], " "))
+			}
+		case diff.Action:
+			before = append(before, g.Bets[g.Round]...)
+			actor, high = g.Actor, g.maxBet()
+			if e.Kind() == 'f' {
+				folded[g.Actor] = g.Round
+			}
+		}
+		g.Update(event)
+		if before == nil {
+			continue
+		}
+		name, bet := h.Players[actor], g.Bets[g.Round][actor]
+		switch a := event.(diff.Action); {
+		case a.Kind() == 'f':
+			fmt.Fprintf(&buf, "%s: folds", name)
+		case a.Kind() == 'c' && bet == before[actor]:
+			fmt.Fprintf(&buf, "%s: checks", name)
+		case a.Kind() == 'c':
+			fmt.Fprintf(&buf, "%s: calls %s", name, chips(bet-before[actor]))
+		case high == 0:
+			fmt.Fprintf(&buf, "%s: bets %s", name, chips(bet))
+		default:
+			fmt.Fprintf(&buf, "%s: raises %s to %s", name, chips(bet-high), chips(bet))
+		}
+		if g.allIn(actor) {
+			buf.WriteString(" and is all-in")
+		}
+		buf.WriteString("\n")
+	}
+	for i, a := range g.Actions {
+		if a != 'f' {
+			folded[i] = -1
+		}
+	}
+
+	// The part of the largest bet that no one called is returned.
+	spent := make([]float64, n)
+	top, second := 0, -1
+	for i := range spent {
+		spent[i] = g.Spent(i)
+		if i != top && spent[i] > spent[top] {
+			top, second = i, top
+		} else if i != top && (second < 0 || spent[i] > spent[second]) {
+			second = i
+		}
+	}
+	if extra := spent[top] - spent[second]; extra > 0 {
+		fmt.Fprintf(&buf, "Uncalled bet (%s) returned to %s\n", chips(extra), h.Players[top])
+		spent[top] -= extra
+	}
+	var pot float64
+	for _, s := range spent {
+		pot += s
+	}
+	showdown := g.NumActive() > 1
+	if showdown {
+		buf.WriteString("*** SHOW DOWN ***\n")
+		for i, hole := range h.Holes {
+			if folded[i] < 0 && len(hole) > 0 {
+				fmt.Fprintf(&buf, "%s: shows [%s]\n", h.Players[i], strings.Join(hole, " "))
+			}
+		}
+	}
+	won := make([]float64, n)
+	for i := range won {
+		if won[i] = h.Results[i] + spent[i]; won[i] > 0 {
+			fmt.Fprintf(&buf, "%s collected %s from pot\n", h.Players[i], chips(won[i]))
+		}
+	}
+
+	fmt.Fprintf(&buf, "*** SUMMARY ***\nTotal pot %s | Rake 0\n", chips(pot))
+	if len(g.Board) > 0 {
+		fmt.Fprintf(&buf, "Board [%s]\n", strings.Join(g.Board, " "))
+	}
+	for i, name := range h.Players {
+		fmt.Fprintf(&buf, "Seat %d: %s", i+1, name)
+		if i == n-1 {
+			buf.WriteString(" (button)")
+		}
+		if b := r.Blind(i); b > 0 {
+			fmt.Fprintf(&buf, " (%s blind)", blindName(b == r.bigBlind()))
+		}
+		hole := strings.Join(h.Holes[i], " ")
+		switch {
+		case folded[i] == PreFlop:
+			buf.WriteString(" folded before Flop")
+		case folded[i] > PreFlop:
+			fmt.Fprintf(&buf, " folded on the %s", street(folded[i]))
+		case showdown && hole != "" && won[i] > 0:
+			fmt.Fprintf(&buf, " showed [%s] and won (%s)", hole, chips(won[i]))
+		case showdown && hole != "":
+			fmt.Fprintf(&buf, " showed [%s] and lost", hole)
+		case showdown && won[i] == 0:
+			buf.WriteString(" mucked")
+		case won[i] > 0:
+			fmt.Fprintf(&buf, " collected (%s)", chips(won[i]))
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n\n")
+	_, err := buf.WriteTo(this.w)
+	return err
+}
+
+func blindName(big bool) string {
+	if big {
+		return "big"
+	}
+	return "small"
+}
+
+// street returns the PokerStars name of a round after the first.
+func street(round int) string {
+	if round-1 < len(streets) {
+		return streets[round-1]
+	}
+	return fmt.Sprintf("Round %d", round)
+}
@@ -0,0 +1,79 @@
+package game
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"poker/game/diff"
+)
+
+func ExamplePokerStarsWriter() {
+	r, _ := ChooseRules("2p-nl")
+	h, _ := diff.ParseHand("STATE:7:r300c/cr900f:AsAd|KcKd/2c7d9h:-300|300:Alice|Bob")
+	w := NewPokerStarsWriter(os.Stdout, r)
+	w.Time = time.Date(2020, 5, 17, 14, 3, 11, 0, time.UTC)
+	w.WriteHand(h)
+	// Output:
+	// PokerStars Hand #7:  Hold'em No Limit (50/100) - 2020/05/17 14:03:11 ET
+	// Table 'two player no limit Texas Hold'em' 2-max Seat #2 is the button
+	// Seat 1: Alice (20000 in chips)
+	// Seat 2: Bob (20000 in chips)
+	// Bob: posts small blind 50
+	// Alice: posts big blind 100
+	// *** HOLE CARDS ***
+	// Dealt to Alice [As Ad]
+	// Dealt to Bob [Kc Kd]
+	// Bob: raises 200 to 300
+	// Alice: calls 200
+	// *** FLOP *** [2c 7d 9h]
+	// Alice: checks
+	// Bob: bets 600
+	// Alice: folds
+	// Uncalled bet (600) returned to Bob
+	// Bob collected 600 from pot
+	// *** SUMMARY ***
+	// Total pot 600 | Rake 0
+	// Board [2c 7d 9h]
+	// Seat 1: Alice (big blind) folded on the Flop
+	// Seat 2: Bob (button) (small blind) collected (600)
+}
+
+func TestPokerStarsWriter(test *testing.T) {
+	r, _ := ChooseRules("2p-l")
+	f, err := os.Open("diff/test-game.log")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer f.Close()
+	log := diff.NewACPCLog(f)
+	buf := new(bytes.Buffer)
+	w := NewPokerStarsWriter(buf, r)
+	var hands []*diff.Hand
+	for {
+		h, err := log.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			test.Fatal(err)
+		}
+		if err := w.WriteHand(h); err != nil {
+			test.Fatal(err)
+		}
+		hands = append(hands, h)
+	}
+	stars := diff.NewPokerStarsLog(buf)
+	for _, h := range hands {
+		h2, err := stars.Next()
+		if err != nil {
+			test.Fatal(err)
+		}
+		h2.Blinds, h2.Stacks = nil, nil
+		if !reflect.DeepEqual(h, h2) {
+			test.Fatalf("Expected\n%v\nbut read back\n%v\n", h, h2)
+		}
+	}
+}
@@ -1,58 +1,332 @@
 package game
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"poker/cards"
+	"poker/game/diff"
+)
+
+// unlimitedRaises is the number of raises allowed in a round when a game does
+// not set maxRaises, as in the ACPC dealer.
+const unlimitedRaises = 255
 
 type Rules struct {
-	descr       string
-	limit       bool
-	numPlayers  int
-	stack       []int
-	blind       []float64
-	raiseSize   []float64
-	firstPlayer []int
-	maxRaises   []int
+	descr         string
+	limit         bool
+	numPlayers    int
+	numRounds     int
+	stack         []int
+	blind         []float64
+	raiseSize     []float64
+	firstPlayer   []int
+	maxRaises     []int
+	numSuits      int
+	numRanks      int
+	numHoleCards  int
+	numBoardCards []int
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]*Rules{
+		"2p-l": {
+			descr:         "two player limit Texas Hold'em",
+			limit:         true,
+			numPlayers:    2,
+			numRounds:     4,
+			blind:         []float64{10, 5},
+			raiseSize:     []float64{10.0, 10.0, 20.0, 20.0},
+			firstPlayer:   []int{2, 1, 1, 1},
+			maxRaises:     []int{3, 4, 4, 4},
+			numSuits:      4,
+			numRanks:      13,
+			numHoleCards:  2,
+			numBoardCards: []int{0, 3, 1, 1},
+		},
+		"3p-l": {
+			descr:         "three player limit Texas Hold'em",
+			limit:         true,
+			numPlayers:    3,
+			numRounds:     4,
+			blind:         []float64{5, 10, 0},
+			raiseSize:     []float64{10.0, 10.0, 20.0, 20.0},
+			firstPlayer:   []int{3, 1, 1, 1},
+			maxRaises:     []int{3, 4, 4, 4},
+			numSuits:      4,
+			numRanks:      13,
+			numHoleCards:  2,
+			numBoardCards: []int{0, 3, 1, 1},
+		},
+		"2p-nl": {
+			descr:         "two player no limit Texas Hold'em",
+			limit:         false,
+			numPlayers:    2,
+			numRounds:     4,
+			stack:         []int{20000, 20000},
+			blind:         []float64{100, 50},
+			firstPlayer:   []int{2, 1, 1, 1},
+			numSuits:      4,
+			numRanks:      13,
+			numHoleCards:  2,
+			numBoardCards: []int{0, 3, 1, 1},
+		},
+		"3p-nl": {
+			descr:         "three player no limit Texas Hold'em",
+			limit:         false,
+			numPlayers:    3,
+			numRounds:     4,
+			stack:         []int{20000, 20000, 20000},
+			blind:         []float64{50, 100, 0},
+			firstPlayer:   []int{3, 1, 1, 1},
+			numSuits:      4,
+			numRanks:      13,
+			numHoleCards:  2,
+			numBoardCards: []int{0, 3, 1, 1},
+		},
+		"kuhn": {
+			descr:         "Kuhn poker",
+			limit:         true,
+			numPlayers:    2,
+			numRounds:     1,
+			blind:         []float64{1, 1},
+			raiseSize:     []float64{1},
+			firstPlayer:   []int{1},
+			maxRaises:     []int{1},
+			numSuits:      1,
+			numRanks:      3,
+			numHoleCards:  1,
+			numBoardCards: []int{0},
+		},
+		"leduc": {
+			descr:         "Leduc Hold'em",
+			limit:         true,
+			numPlayers:    2,
+			numRounds:     2,
+			blind:         []float64{1, 1},
+			raiseSize:     []float64{2, 4},
+			firstPlayer:   []int{1, 1},
+			maxRaises:     []int{2, 2},
+			numSuits:      2,
+			numRanks:      3,
+			numHoleCards:  1,
+			numBoardCards: []int{0, 1},
+		},
+	}
+)
+
+// RegisterRules makes a set of rules available by name to ChooseRules and
+// NewGame. If the rules have no description, the name is used. It panics if
+// the name is already taken.
+func RegisterRules(name string, r *Rules) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if r == nil {
+		panic("game: RegisterRules rules are nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("game: RegisterRules called twice for " + name)
+	}
+	if r.descr == "" {
+		c := *r
+		c.descr = name
+		r = &c
+	}
+	registry[name] = r
 }
 
+// RulesNames returns the sorted names of all of the registered rules.
+func RulesNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ChooseRules returns the rules registered with the given name or, if the
+// name ends in ".game", the rules read from that ACPC game definition file.
+// Rules can not be changed once they are made, so they may be shared.
 func ChooseRules(rules string) (*Rules, error) {
-	switch rules {
-	case "2p-l":
-		return &Rules{
-			descr:       "two player limit Texas Hold'em",
-			limit:       true,
-			numPlayers:  2,
-			blind:       []float64{10, 5},
-			raiseSize:   []float64{10.0, 10.0, 20.0, 20.0},
-			firstPlayer: []int{2, 1, 1, 1},
-			maxRaises:   []int{3, 4, 4, 4},
-		}, nil
-	case "3p-l":
-		return &Rules{
-			descr:       "three player limit Texas Hold'em",
-			limit:       true,
-			numPlayers:  3,
-			blind:       []float64{5, 10, 0},
-			raiseSize:   []float64{10.0, 10.0, 20.0, 20.0},
-			firstPlayer: []int{3, 1, 1, 1},
-			maxRaises:   []int{3, 4, 4, 4},
-		}, nil
-	case "2p-nl":
-		return &Rules{
-			descr:       "two player no limit Texas Hold'em",
-			limit:       false,
-			numPlayers:  2,
-			stack:       []int{20000, 20000},
-			blind:       []float64{100, 50},
-			firstPlayer: []int{2, 1, 1, 1},
-		}, nil
-	case "3p-nl":
-		return &Rules{
-			descr:       "three player no limit Texas Hold'em",
-			limit:       false,
-			numPlayers:  3,
-			stack:       []int{20000, 20000, 20000},
-			blind:       []float64{50, 100, 0},
-			firstPlayer: []int{3, 1, 1, 1},
-		}, nil
+	registryMu.RLock()
+	r, ok := registry[rules]
+	registryMu.RUnlock()
+	if ok {
+		return r, nil
+	}
+	if strings.HasSuffix(rules, ".game") {
+		return LoadRules(rules)
 	}
 	return nil, fmt.Errorf("Don't know how to play %s\n", rules)
 }
+
+// HandRules returns r changed to fit a hand from a hand history: the number of
+// players, blinds, and, in no limit games, stacks are those of h, and limit
+// raises are in proportion to its big blind. It returns r if h does not give
+// its blinds.
+func HandRules(r *Rules, h *diff.Hand) *Rules {
+	if len(h.Blinds) == 0 {
+		return r
+	}
+	c := *r
+	n := len(h.Blinds)
+	c.numPlayers = n
+	c.blind = append([]float64(nil), h.Blinds...)
+	big := 0
+	for i, b := range c.blind {
+		if b >= c.blind[big] {
+			big = i
+		}
+	}
+	// The player after the big blind acts first.
+	c.firstPlayer = append([]int{(big+1)%n + 1}, r.firstPlayer[1:]...)
+	if r.limit {
+		c.raiseSize = make([]float64, len(r.raiseSize))
+		for i, size := range r.raiseSize {
+			c.raiseSize[i] = size * c.blind[big] / r.bigBlind()
+		}
+	} else if len(h.Stacks) == n {
+		c.stack = make([]int, n)
+		for i, chips := range h.Stacks {
+			c.stack[i] = int(chips)
+		}
+	}
+	return &c
+}
+
+// bigBlind returns the largest blind.
+func (this *Rules) bigBlind() float64 {
+	var big float64
+	for _, b := range this.blind {
+		big = math.Max(big, b)
+	}
+	return big
+}
+
+// smallBlind returns the smallest blind that is posted.
+func (this *Rules) smallBlind() float64 {
+	small := this.bigBlind()
+	for _, b := range this.blind {
+		if b > 0 && b < small {
+			small = b
+		}
+	}
+	return small
+}
+
+// Description returns a short human readable description of the game.
+func (this *Rules) Description() string {
+	if this.descr != "" {
+		return this.descr
+	}
+	if this.limit {
+		return fmt.Sprintf("%d player limit poker", this.numPlayers)
+	}
+	return fmt.Sprintf("%d player no limit poker", this.numPlayers)
+}
+
+// Limit reports whether the game is a limit game. If it is not, then it is a
+// no limit game.
+func (this *Rules) Limit() bool {
+	return this.limit
+}
+
+func (this *Rules) NumPlayers() int {
+	return this.numPlayers
+}
+
+func (this *Rules) NumRounds() int {
+	return this.numRounds
+}
+
+// Stack returns the starting stack of the player in seat. Limit games have no
+// stacks, so it returns 0 for them.
+func (this *Rules) Stack(seat int) int {
+	if seat < len(this.stack) {
+		return this.stack[seat]
+	}
+	return 0
+}
+
+// Stacks returns a copy of the starting stacks of all of the players.
+func (this *Rules) Stacks() []int {
+	return append([]int(nil), this.stack...)
+}
+
+// Blind returns the blind posted by the player in seat.
+func (this *Rules) Blind(seat int) float64 {
+	if seat < len(this.blind) {
+		return this.blind[seat]
+	}
+	return 0
+}
+
+// Blinds returns a copy of the blinds posted by all of the players.
+func (this *Rules) Blinds() []float64 {
+	return append([]float64(nil), this.blind...)
+}
+
+// RaiseSize returns the size of a raise in round of a limit game.
+func (this *Rules) RaiseSize(round int) float64 {
+	if round < len(this.raiseSize) {
+		return this.raiseSize[round]
+	}
+	return 0
+}
+
+// FirstPlayer returns the seat of the player who acts first in round. Seats
+// are numbered from 0, unlike in an ACPC game definition.
+func (this *Rules) FirstPlayer(round int) int {
+	if round < len(this.firstPlayer) {
+		return this.firstPlayer[round] - 1
+	}
+	return 0
+}
+
+// MaxRaises returns the number of raises allowed in round.
+func (this *Rules) MaxRaises(round int) int {
+	if round < len(this.maxRaises) {
+		return this.maxRaises[round]
+	}
+	return unlimitedRaises
+}
+
+func (this *Rules) NumSuits() int {
+	return this.numSuits
+}
+
+func (this *Rules) NumRanks() int {
+	return this.numRanks
+}
+
+// NumHoleCards returns the number of private cards dealt to each player.
+func (this *Rules) NumHoleCards() int {
+	return this.numHoleCards
+}
+
+// NumBoardCards returns the number of public cards dealt in round.
+func (this *Rules) NumBoardCards(round int) int {
+	if round < len(this.numBoardCards) {
+		return this.numBoardCards[round]
+	}
+	return 0
+}
+
+// Deck returns the cards the game is played with. As in the ACPC dealer, a
+// game with fewer than 13 ranks or 4 suits uses the lowest of each.
+func (this *Rules) Deck() []string {
+	deck := make([]string, 0, this.numRanks*this.numSuits)
+	for i := 0; i < this.numRanks; i++ {
+		for j := 0; j < this.numSuits; j++ {
+			deck = append(deck, string([]byte{cards.Ranks[i], cards.Suits[j]}))
+		}
+	}
+	return deck
+}
@@ -0,0 +1,165 @@
+package game
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+
+	"poker/cards"
+	"poker/game/diff"
+)
+
+// A State is a snapshot of the parts of a Game that change during a hand. It
+// is a plain value, so it can be copied by assignment, compared with ==, used
+// as a map key, and serialized with MarshalBinary. Chips are stored exactly,
+// fractions and all, and cards by their index in the deck, with 0 for no card.
+type State struct {
+	Round     int8
+	Actor     int8
+	LastActor int8
+	Raises    uint8
+	LastRaise float64
+	NumHoles  uint8
+	NumBoard  uint8
+	Actions   [MaxPlayers]byte
+	Bets      [MaxRounds][MaxPlayers]float64
+	Holes     [MaxPlayers * MaxHoleCards]byte
+	Hands     [MaxPlayers][MaxHoleCards]byte
+	Board     [MaxBoardCards]byte
+}
+
+// State returns a snapshot of the game.
+func (this *Game) State() State {
+	var s State
+	s.Round = int8(this.Round)
+	s.Actor = int8(this.Actor)
+	s.LastActor = int8(this.LastActor)
+	s.Raises = uint8(this.Raises)
+	s.LastRaise = this.LastRaise
+	copy(s.Actions[:], this.Actions)
+	for i, bets := range this.Bets {
+		copy(s.Bets[i][:], bets)
+	}
+	for i, c := range cards.StoI(this.Holes) {
+		s.Holes[i] = byte(c)
+	}
+	s.NumHoles = uint8(len(this.Holes))
+	for i, hand := range this.Hands {
+		for j, c := range cards.StoI(hand) {
+			s.Hands[i][j] = byte(c)
+		}
+	}
+	for i, c := range cards.StoI(this.Board) {
+		s.Board[i] = byte(c)
+	}
+	s.NumBoard = uint8(len(this.Board))
+	return s
+}
+
+// SetState returns the game to a snapshot taken with State. The rules,
+// players, and most recent event are left unchanged.
+func (this *Game) SetState(s State) {
+	this.Round = int(s.Round)
+	this.Actor = int(s.Actor)
+	this.LastActor = int(s.LastActor)
+	this.Raises = int(s.Raises)
+	this.LastRaise = s.LastRaise
+	copy(this.Actions, s.Actions[:])
+	for i, bets := range this.Bets {
+		copy(bets, s.Bets[i][:])
+	}
+	this.Holes = unpackCards(s.Holes[:s.NumHoles])
+	this.Hands = nil
+	if this.Round >= PreFlop {
+		this.Hands = make([][]string, len(this.Actions))
+		for i := range this.Hands {
+			n := bytes.IndexByte(s.Hands[i][:], 0)
+			if n < 0 {
+				n = MaxHoleCards
+			}
+			this.Hands[i] = unpackCards(s.Hands[i][:n])
+		}
+	}
+	this.Board = unpackCards(s.Board[:s.NumBoard])
+}
+
+func unpackCards(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	ints := make([]int32, len(b))
+	for i, c := range b {
+		ints[i] = int32(c)
+	}
+	return cards.ItoS(ints)
+}
+
+// MarshalBinary encodes the state in a fixed size, machine independent form.
+func (s State) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := binary.Write(buf, binary.LittleEndian, &s); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a state encoded with MarshalBinary.
+func (s *State) UnmarshalBinary(data []byte) error {
+	return binary.Read(bytes.NewReader(data), binary.LittleEndian, s)
+}
+
+// Hash returns a 64-bit FNV-1a hash of the state.
+func (s State) Hash() uint64 {
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, &s)
+	return h.Sum64()
+}
+
+// A step is everything needed to return a game to how it was before an event.
+type step struct {
+	state   State
+	event   interface{}
+	players *diff.Players
+}
+
+func (this *Game) step() step {
+	return step{this.State(), this.Event, this.Players}
+}
+
+func (this *Game) setStep(s step) {
+	this.SetState(s.state)
+	this.Event = s.event
+	this.Players = s.players
+}
+
+// Apply updates the game with an event so that the update can be undone. It
+// lets a depth-first search walk a game tree without copying the game.
+func (this *Game) Apply(event interface{}) {
+	this.undo = append(this.undo, this.step())
+	this.redo = this.redo[:0]
+	this.Update(event)
+}
+
+// Undo reverses the most recent Apply. It returns false if there is nothing
+// to undo.
+func (this *Game) Undo() bool {
+	if len(this.undo) == 0 {
+		return false
+	}
+	this.redo = append(this.redo, this.step())
+	this.setStep(this.undo[len(this.undo)-1])
+	this.undo = this.undo[:len(this.undo)-1]
+	return true
+}
+
+// Redo reverses the most recent Undo. It returns false if there is nothing to
+// redo.
+func (this *Game) Redo() bool {
+	if len(this.redo) == 0 {
+		return false
+	}
+	this.undo = append(this.undo, this.step())
+	this.setStep(this.redo[len(this.redo)-1])
+	this.redo = this.redo[:len(this.redo)-1]
+	return true
+}
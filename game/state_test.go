@@ -0,0 +1,81 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"poker/game/diff"
+)
+
+func TestState(test *testing.T) {
+	g, err := NewGame("2p-l")
+	if err != nil {
+		test.Fatal(err)
+	}
+	play(g, &diff.Players{Viewer: 0}, diff.Cards("AsKd|"), diff.Action("r"))
+	s := g.State()
+	g2 := g.Copy()
+	play(g, diff.Action("c"), diff.Cards("/2c3c4c"))
+	if g.State() == s || g2.State() != s {
+		test.Fatalf("Updating a game changed its copy\n")
+	}
+	g.SetState(s)
+	if g.State() != s || g.String() != g2.String() {
+		test.Fatalf("Expected\n%vbut got\n%v", g2, g)
+	}
+	data, err := s.MarshalBinary()
+	if err != nil {
+		test.Fatal(err)
+	}
+	var s2 State
+	if err := s2.UnmarshalBinary(data); err != nil {
+		test.Fatal(err)
+	}
+	if s2 != s || s2.Hash() != s.Hash() {
+		test.Fatalf("Decoded %+v, but expected %+v\n", s2, s)
+	}
+}
+
+func TestCopy(test *testing.T) {
+	g, err := NewGame("2p-nl")
+	if err != nil {
+		test.Fatal(err)
+	}
+	g.Apply(&diff.Players{Viewer: 0})
+	g.Apply(diff.Cards("AsKd|"))
+	// Chips from a hand history may be fractions of a blind.
+	g.Bets[PreFlop][0] = 2.5
+	g.LastRaise = 1.5
+	g.Deadline = time.Now().Add(time.Minute)
+	c := g.Copy()
+	if c.Bets[PreFlop][0] != 2.5 || c.LastRaise != 1.5 || !c.Deadline.Equal(g.Deadline) {
+		test.Fatalf("Expected a copy of\n%+v\nbut got\n%+v\n", g, c)
+	}
+	if c.Undo() || !g.Undo() {
+		test.Fatalf("Expected only the original to have history to undo\n")
+	}
+}
+
+func TestUndo(test *testing.T) {
+	g, err := NewGame("leduc")
+	if err != nil {
+		test.Fatal(err)
+	}
+	g.Apply(&diff.Players{Viewer: 0})
+	g.Apply(diff.Cards("3c|"))
+	s := g.State()
+	g.Apply(diff.Action("r"))
+	g.Apply(diff.Action("c"))
+	after := g.State()
+	if !g.Undo() || !g.Undo() || g.State() != s || g.Event != diff.Cards("3c|") {
+		test.Fatalf("Undoing two actions did not return to\n%+v\n", s)
+	}
+	if !g.Redo() || !g.Redo() || g.Redo() || g.State() != after {
+		test.Fatalf("Redoing two actions did not return to\n%+v\n", after)
+	}
+	for g.Undo() {
+	}
+	if g.Players != nil {
+		test.Fatalf("Undoing everything did not return to a new game\n")
+	}
+}
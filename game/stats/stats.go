@@ -0,0 +1,227 @@
+// Package stats counts how players play from their hand histories: how much
+// they win, and the usual measures of their style, such as VPIP and PFR,
+// overall, by position, and by round.
+package stats
+
+import (
+	"fmt"
+	"math"
+
+	"poker/game"
+	"poker/game/diff"
+)
+
+// Counts holds how often a player did things in some set of hands. The
+// percentages and ratios are worked out from these by its methods.
+type Counts struct {
+	Hands int
+	won   float64 // The sum of the big blinds won in each hand.
+	won2  float64 // The sum of their squares.
+
+	VPIP int // Hands where chips were put in the pot voluntarily before the flop.
+	PFR  int // Hands raised before the flop.
+
+	// The actions taken in each round.
+	Raises, Calls, Checks, Folds [game.MaxRounds]int
+
+	CbetFaced  int // Times facing a continuation bet on the flop.
+	CbetFolded int // Times folding to one.
+
+	SawFlop      int // Hands where the flop was seen.
+	Showdowns    int // Hands that went to the showdown.
+	ShowdownsWon int // Showdowns won.
+}
+
+// add counts the result of a hand in big blinds.
+func (this *Counts) add(bb float64) {
+	this.Hands++
+	this.won += bb
+	this.won2 += bb * bb
+}
+
+// MBB returns the mean milli big blinds won per hand and the half width of a
+// 95% confidence interval for it.
+func (this *Counts) MBB() (mean, ci float64) {
+	if this.Hands == 0 {
+		return 0, 0
+	}
+	n := float64(this.Hands)
+	mean = this.won / n
+	if this.Hands > 1 {
+		variance := math.Max(0, (this.won2-n*mean*mean)/(n-1))
+		ci = 1.96 * math.Sqrt(variance/n)
+	}
+	return 1000 * mean, 1000 * ci
+}
+
+// AF returns the aggression factor, the raises per call, after the flop, or
+// in round if round is not -1.
+func (this *Counts) AF(round int) float64 {
+	var raises, calls int
+	for r := range this.Raises {
+		if r == round || (round == -1 && r > game.PreFlop) {
+			raises += this.Raises[r]
+			calls += this.Calls[r]
+		}
+	}
+	return ratio(raises, calls)
+}
+
+// Percent returns n as a percentage of total.
+func Percent(n, total int) float64 {
+	return 100 * ratio(n, total)
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return math.NaN()
+	}
+	return float64(n) / float64(d)
+}
+
+// Stats holds the counts of each player in all of the hands added to it.
+type Stats struct {
+	Players    []string // Every player, in the order they were first seen.
+	All        map[string]*Counts
+	ByPosition map[string]map[string]*Counts // The counts of each player by position.
+	rules      *game.Rules
+}
+
+// New returns empty stats for hands played with rules r.
+func New(r *game.Rules) *Stats {
+	return &Stats{
+		All:        make(map[string]*Counts),
+		ByPosition: make(map[string]map[string]*Counts),
+		rules:      r,
+	}
+}
+
+// Positions lists the names Position gives, in the order the seats act after
+// the flop.
+var Positions = []string{"SB", "BB", "UTG", "MP", "CO", "BTN"}
+
+// Position returns the usual name of a seat at a table of n: BTN for the
+// button, SB and BB for the blinds, CO for the seat before the button, and UTG
+// and MP for the rest.
+func Position(seat, n int) string {
+	switch {
+	case seat == n-1:
+		return "BTN"
+	case n == 2:
+		return "BB"
+	case seat == 0:
+		return "SB"
+	case seat == 1:
+		return "BB"
+	case seat == n-2 && n > 4:
+		return "CO"
+	case seat == 2:
+		return "UTG"
+	}
+	return "MP"
+}
+
+// counts returns the counts of a player overall and in a position.
+func (this *Stats) counts(name, position string) (*Counts, *Counts) {
+	all, ok := this.All[name]
+	if !ok {
+		all = new(Counts)
+		this.All[name] = all
+		this.ByPosition[name] = make(map[string]*Counts)
+		this.Players = append(this.Players, name)
+	}
+	pos, ok := this.ByPosition[name][position]
+	if !ok {
+		pos = new(Counts)
+		this.ByPosition[name][position] = pos
+	}
+	return all, pos
+}
+
+// Add counts a hand. It is replayed to find out who took each action.
+func (this *Stats) Add(h *diff.Hand) error {
+	r := game.HandRules(this.rules, h)
+	n := r.NumPlayers()
+	if len(h.Players) != n || len(h.Results) != n {
+		return fmt.Errorf("stats: hand %d does not have %d players", h.Num, n)
+	}
+	var bb float64
+	for _, b := range r.Blinds() {
+		bb = math.Max(bb, b)
+	}
+	if bb == 0 {
+		return fmt.Errorf("stats: hand %d has no blinds", h.Num)
+	}
+	vpip, pfr := make([]bool, n), make([]bool, n)
+	folded := make([]int, n) // The round each seat folded in, or -1.
+	for i := range folded {
+		folded[i] = -1
+	}
+	// The last raiser before the flop, and whether they have made the first
+	// raise on the flop, a continuation bet, that no one has raised since.
+	aggressor, cbet, flopRaised := -1, false, false
+	g := game.NewGameRules(r)
+	for _, event := range h.Events(0) {
+		a, ok := event.(diff.Action)
+		if !ok {
+			g.Update(event)
+			continue
+		}
+		seat, round, call := g.Actor, g.Round, g.CallAmt()
+		all, pos := this.counts(h.Players[seat], Position(seat, n))
+		for _, c := range []*Counts{all, pos} {
+			switch {
+			case a.Kind() == 'r':
+				c.Raises[round]++
+			case a.Kind() == 'f':
+				c.Folds[round]++
+			case call > 0:
+				c.Calls[round]++
+			default:
+				c.Checks[round]++
+			}
+			if round == game.Flop && cbet && seat != aggressor {
+				c.CbetFaced++
+				if a.Kind() == 'f' {
+					c.CbetFolded++
+				}
+			}
+		}
+		switch {
+		case a.Kind() == 'f':
+			folded[seat] = round
+		case round == game.PreFlop && a.Kind() == 'r':
+			vpip[seat], pfr[seat] = true, true
+			aggressor = seat
+		case round == game.PreFlop && call > 0:
+			vpip[seat] = true
+		case round == game.Flop && a.Kind() == 'r':
+			cbet = !flopRaised && seat == aggressor
+			flopRaised = true
+		}
+		g.Update(event)
+	}
+	showdown := g.NumActive() > 1
+	for seat, name := range h.Players {
+		all, pos := this.counts(name, Position(seat, n))
+		for _, c := range []*Counts{all, pos} {
+			c.add(h.Results[seat] / bb)
+			if vpip[seat] {
+				c.VPIP++
+			}
+			if pfr[seat] {
+				c.PFR++
+			}
+			if len(h.Actions) > game.Flop && folded[seat] != game.PreFlop {
+				c.SawFlop++
+			}
+			if showdown && g.Actions[seat] != 'f' {
+				c.Showdowns++
+				if h.Results[seat] > 0 {
+					c.ShowdownsWon++
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package stats
+
+import (
+	"math"
+	"testing"
+
+	"poker/game"
+	"poker/game/diff"
+)
+
+func TestStats(test *testing.T) {
+	r, _ := game.ChooseRules("2p-l")
+	s := New(r)
+	for _, state := range []string{
+		"STATE:0:rc/rf:AsKd|2c3d/4h5h6h:20|-20:a|b",
+		"STATE:1:rc/crf:AsKd|2c3d/4h5h6h:-20|20:a|b",
+		"STATE:2:cc/cc/cc/cc:AsKd|2c3d/4h5h9h/Tc/Jd:10|-10:a|b",
+	} {
+		h, err := diff.ParseHand(state)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if err := s.Add(h); err != nil {
+			test.Fatal(err)
+		}
+	}
+	a, b := s.All["a"], s.All["b"]
+	if a.Hands != 3 || a.VPIP != 2 || a.PFR != 0 || b.VPIP != 3 || b.PFR != 2 {
+		test.Fatalf("Expected VPIP 2 and 3 and PFR 0 and 2, but got %+v and %+v\n", a, b)
+	}
+	if a.CbetFaced != 1 || a.CbetFolded != 1 || b.CbetFaced != 0 {
+		test.Fatalf("Expected a to fold to one continuation bet, but got %+v\n", a)
+	}
+	if a.SawFlop != 3 || a.Showdowns != 1 || a.ShowdownsWon != 1 || b.ShowdownsWon != 0 {
+		test.Fatalf("Expected a to win one showdown, but got %+v\n", a)
+	}
+	if mbb, _ := a.MBB(); math.Abs(mbb-1000.0/3) > 1e-9 {
+		test.Fatalf("Expected a to win 333 mbb/hand, but got %v\n", mbb)
+	}
+	if af := b.AF(game.PreFlop); af != 2 || !math.IsNaN(b.AF(-1)) {
+		test.Fatalf("Expected b to raise twice per call before the flop and never call after it, but got %v\n", af)
+	}
+	if c := s.ByPosition["a"]["BB"]; c == nil || c.Hands != 3 {
+		test.Fatalf("Expected a to play 3 hands in the big blind, but got %+v\n", c)
+	}
+}
@@ -0,0 +1,83 @@
+// Histconv converts hand histories between ACPC logs, PokerStars hand
+// histories, and JSON hands. It reads the files given, or the standard input
+// if there are none, and writes to the standard output.
+//
+//	histconv [flags] [file ...]
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"poker/game"
+	"poker/game/diff"
+)
+
+func newWriter(format string, w io.Writer, r *game.Rules) (diff.HandWriter, error) {
+	switch format {
+	case "acpc":
+		return diff.NewACPCLogWriter(w), nil
+	case "pokerstars":
+		return game.NewPokerStarsWriter(w, r), nil
+	case "json":
+		return game.NewJSONWriter(w, r), nil
+	}
+	return nil, fmt.Errorf("The format %s is not one of %s.", format, diff.HistoryFormats)
+}
+
+func main() {
+	rules := flag.String("rules", "2p-l", "What rules the hands were played with: "+
+		strings.Join(game.RulesNames(), ", ")+", or an ACPC .game file.")
+	from := flag.String("from", "acpc", "The format to read: "+diff.HistoryFormats+".")
+	to := flag.String("to", "json", "The format to write: "+diff.HistoryFormats+".")
+	flag.Parse()
+	r, err := game.ChooseRules(*rules)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	w, err := newWriter(*to, out, r)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	var inputs []io.Reader
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		inputs = append(inputs, f)
+	}
+	if len(inputs) == 0 {
+		inputs = append(inputs, os.Stdin)
+	}
+	for _, in := range inputs {
+		hands, err := diff.NewHandReader(*from, in)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for {
+			h, err := hands.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				out.Flush()
+				log.Fatalln(err)
+			}
+			if err := w.WriteHand(h); err != nil {
+				out.Flush()
+				log.Fatalln(err)
+			}
+		}
+	}
+	if acpc, ok := w.(*diff.ACPCLogWriter); ok {
+		acpc.WriteScore()
+	}
+}
@@ -1,98 +1,207 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"math/rand"
 	"log"
+	"net"
 	"os"
+	"path/filepath"
 	"runtime/pprof"
 	"strings"
+	"sync"
 
-	"poker/equity"
 	"poker/game"
 	"poker/game/diff"
+	"poker/game/stats"
+	"poker/strategy"
+	_ "poker/strategy/ev"
+	_ "poker/strategy/hs"
 )
 
-
-// The randPlayer chooses an action uniform randomly from all legal actions each
-// turn.
-type randPlayer struct {
-	Name string
+// A tally adds up the hands played at every table.
+type tally struct {
+	mu    sync.Mutex
+	stats *stats.Stats
+	won   []float64 // The chips won at each table.
 }
 
-func (_ *randPlayer) Observe(_ *game.Game) {}
-
-func (_ *randPlayer) Play(g *game.Game) string {
-	a := g.LegalActions()
-	return string(a[rand.Intn(len(a))])
+// A tableLog records the hands played at one table in a tally, and in the
+// table's own ACPC log if it has one.
+type tableLog struct {
+	*tally
+	table int
+	log   *diff.ACPCLogWriter
 }
 
-
-// stratPlayer chooses the action which has the greatest EV based on the 7cHS,
-// pot odds, and an implied call. An an opponent call is included in the EV,
-// because in a two player game if the oppenent does not call, the player will
-// outright win the pot.
-type stratPlayer struct {
-	Name   string
-	equity float64
+func (this tableLog) WriteHand(h *diff.Hand) error {
+	this.mu.Lock()
+	this.won[this.table] += h.Results[h.Seat("Hob2")]
+	err := this.stats.Add(h)
+	this.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if this.log != nil {
+		return this.log.WriteHand(h)
+	}
+	return nil
 }
 
-func (this *stratPlayer) Observe(g *game.Game) {
-	if _, ok := g.Event.(diff.Cards); ok && g.Round != 4 {
-		this.equity = equity.HandEquity(g.Holes, g.Board, 1000)
+// logName returns the name of the log of the table at port: name itself if
+// there is one table, or name with the port before its extension otherwise.
+func logName(name, port string, tables int) string {
+	if tables == 1 {
+		return name
 	}
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-" + port + ext
 }
 
-func (this *stratPlayer) Play(g *game.Game) string {
-	if _, ok := g.Event.(diff.Cards); ok {
-		this.equity = equity.HandEquity(g.Holes, g.Board, 1000)
+// playTable plays a match with a new player at the dealer at addr, and writes
+// its hands to out.
+func playTable(r *game.Rules, strat string, params strategy.Params, addr string,
+	limits game.TimeLimits, out diff.HandWriter) error {
+	player, err := strategy.New(strat, "Hob2", params)
+	if err != nil {
+		return err
 	}
-
-	max := 0.0 // Folding has EV = 0
-	action := "f"
-	c := g.CallAmt()
-	r := g.RaiseAmt()
-	pot := g.Pot()
-	if ev := (this.equity * (pot + c)) - c; ev >= max {
-		action = "c"
-		max = ev
-	}
-	if (this.equity*(pot + 2*r - c)) - r >= max {
-		action = "r"
-	}
-	if strings.Contains(g.LegalActions(), action) {
-		return action
-	}
-	return "c"
+	engine, err := diff.DialACPC(context.Background(), addr, diff.ACPCOptions{})
+	if err != nil {
+		return err
+	}
+	return game.PlayTimed(engine, r, game.LogHands(player, out, "Hob2"), limits)
 }
 
-func chooseStrat(name, strat string) (game.Player, error) {
-	switch strat {
-	case "random":
-		return &randPlayer{name}, nil
-	case "7cHS":
-		return &stratPlayer{Name: name}, nil
-	}
-	return nil, fmt.Errorf("The strategy %s was not found.", strat)
+func main() {
+	os.Exit(run())
 }
 
-func main() {
+// run plays the matches and returns the exit code, so that the deferred
+// profile and logs are finished first.
+func run() int {
 	prof := flag.Bool("prof", false, "Create a pprof profile.")
-	rules := flag.String("rules", "2p-l", "What rules to use.")
-	strat := flag.String("strat", "7cHS", "What strategy to use.")
+	rules := flag.String("rules", "2p-l", "What rules to use: "+
+		strings.Join(game.RulesNames(), ", ")+", or an ACPC .game file.")
+	strat := flag.String("strat", "7cHS", "What strategy to use: "+strings.Join(strategy.Names(), ", ")+".")
+	paramList := flag.String("params", "", "The strategy's parameters, such as \"trials=500\".")
+	config := flag.String("config", "", "A file of the strategy's parameters, one key=value pair per line.")
+	list := flag.Bool("strats", false, "Describe the strategies and their parameters, and exit.")
+	logFile := flag.String("log", "", "Write an ACPC log of the match to this file. With several "+
+		"ports, each table gets its own log, with the port added to the name, such as hob2-9001.log.")
+	var limits game.TimeLimits
+	flag.DurationVar(&limits.Action, "action-time", 0, "The most time to take for one action. 0 means no limit.")
+	flag.DurationVar(&limits.Hand, "hand-time", 0, "The most time to take for all of the actions in a hand.")
+	flag.DurationVar(&limits.Match, "match-time", 0, "The most time to take for all of the actions in the match.")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: hob2 [flags] host port [port ...]")
+		fmt.Fprintln(os.Stderr, "Plays a match at each port of the dealer at host at the same time.")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
+	if *list {
+		for _, name := range strategy.Names() {
+			fmt.Printf("%s: %s\n", name, strategy.About(name))
+		}
+		return 0
+	}
+	if flag.NArg() < 2 {
+		flag.Usage()
+		return 2
+	}
+	params, err := strategy.ParseParams(*paramList)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	if *config != "" {
+		f, err := os.Open(*config)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		fileParams, err := strategy.ReadParams(f)
+		f.Close()
+		if err != nil {
+			log.Printf("%s: %v\n", *config, err)
+			return 1
+		}
+		// Parameters given with -params override those in the file.
+		for k, v := range params {
+			fileParams[k] = v
+		}
+		params = fileParams
+	}
+	// Check the strategy and its parameters before connecting.
+	if _, err := strategy.New(*strat, "Hob2", params); err != nil {
+		log.Println("Failed to create player:", err)
+		return 1
+	}
+	r, err := game.ChooseRules(*rules)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	host, ports := flag.Arg(0), flag.Args()[1:]
+	t := &tally{stats: stats.New(r), won: make([]float64, len(ports))}
+	tables := make([]tableLog, len(ports))
+	for i, port := range ports {
+		tables[i] = tableLog{tally: t, table: i}
+		if *logFile == "" {
+			continue
+		}
+		f, err := os.Create(logName(*logFile, port, len(ports)))
+		if err != nil {
+			log.Println("Failed to create log:", err)
+			return 1
+		}
+		defer f.Close()
+		tables[i].log = diff.NewACPCLogWriter(f)
+		tables[i].log.Comment(fmt.Sprintf("Hob2 playing %s at %s", r.Description(),
+			net.JoinHostPort(host, port)))
+	}
 	if *prof {
 		f, err := os.Create("hob2.prof")
 		if err != nil {
-			log.Fatalln("Failed to create profile:", err)
+			log.Println("Failed to create profile:", err)
+			return 1
 		}
+		defer f.Close()
 		pprof.StartCPUProfile(f)
 		defer pprof.StopCPUProfile()
 	}
-	player, err := chooseStrat("Hob2", *strat)
-	if err != nil {
-		log.Fatalln("Failed to create player:", err)
+	errs := make([]error, len(ports))
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		addr := net.JoinHostPort(host, port)
+		fmt.Printf("Connecting to dealer at %s to play %s...\n", addr, *rules)
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			errs[i] = playTable(r, *strat, params, addr, limits, tables[i])
+		}(i, addr)
+	}
+	wg.Wait()
+	for _, table := range tables {
+		if table.log != nil {
+			table.log.WriteScore()
+		}
+	}
+	fmt.Println("GAME OVER")
+	code := 0
+	for i, port := range ports {
+		fmt.Printf("Table %d (port %s): won %g", i+1, port, t.won[i])
+		if errs[i] != nil {
+			fmt.Printf(", ended early: %v", errs[i])
+			code = 1
+		}
+		fmt.Println()
+	}
+	if c, ok := t.stats.All["Hob2"]; ok {
+		mbb, ci := c.MBB()
+		fmt.Printf("Hob2: %d hands, %.0f ± %.0f mbb/hand, VPIP %.1f%%, PFR %.1f%%, AF %.2f\n",
+			c.Hands, mbb, ci, stats.Percent(c.VPIP, c.Hands), stats.Percent(c.PFR, c.Hands), c.AF(-1))
 	}
-	game.Play(*rules, player, flag.Arg(0), flag.Arg(1))
+	return code
 }
@@ -0,0 +1,260 @@
+// Human lets a person play against bots from the terminal. It connects to an
+// ACPC dealer, such as one run by the dealer command on localhost, shows the
+// table after each action, and reads the player's actions from the standard
+// input. With a URL instead of a host and port, it joins a dealer run with
+// -http.
+//
+//	human [flags] host port
+//	human [flags] http://host:port
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"poker/game"
+	"poker/game/diff"
+)
+
+// A human is a Player who is asked what to do at the terminal.
+type human struct {
+	in     *bufio.Scanner
+	out    io.Writer
+	before float64 // The chips the seat to act had put in the pot.
+	hand   int
+	won    float64
+}
+
+func newHuman(in io.Reader, out io.Writer) *human {
+	return &human{in: bufio.NewScanner(in), out: out}
+}
+
+// name returns the name to show for seat.
+func (this *human) name(g *game.Game, seat int) string {
+	switch {
+	case seat == g.Viewer:
+		return "You"
+	case seat < len(g.Names):
+		return g.Names[seat]
+	}
+	return fmt.Sprintf("Seat %d", seat+1)
+}
+
+func roundName(round int) string {
+	if round < len(game.RoundNames) {
+		return game.RoundNames[round]
+	}
+	return fmt.Sprintf("round %d", round+1)
+}
+
+func (this *human) HandStarted(g *game.Game) {
+	this.hand++
+	fmt.Fprintf(this.out, "\n=== Hand %d ===\n", this.hand)
+}
+
+func (this *human) HandEnded(g *game.Game, results []float64) {
+	for seat, chips := range results {
+		switch {
+		case chips > 0:
+			fmt.Fprintf(this.out, "%s won %g.\n", this.name(g, seat), chips)
+		case chips < 0 && seat == g.Viewer:
+			fmt.Fprintf(this.out, "You lost %g.\n", -chips)
+		}
+	}
+	this.won += results[g.Viewer]
+	fmt.Fprintf(this.out, "You have won %g in %d hands.\n", this.won, this.hand)
+}
+
+func (this *human) Observe(g *game.Game) {
+	this.show(g)
+	this.turn(g)
+}
+
+// turn remembers the chips put in by the seat to act, to describe its action.
+func (this *human) turn(g *game.Game) {
+	if g.Actor >= 0 {
+		this.before = g.Spent(g.Actor)
+	}
+}
+
+// show describes the latest event.
+func (this *human) show(g *game.Game) {
+	switch e := g.Event.(type) {
+	case diff.Action:
+		actor := g.LastActor
+		if actor == g.Viewer {
+			return
+		}
+		verb := map[byte]string{'f': "folds", 'c': "calls", 'r': "raises"}[e.Kind()]
+		if e.Kind() == 'c' && g.Spent(actor) == this.before {
+			verb = "checks"
+		}
+		fmt.Fprintf(this.out, "%s %s", this.name(g, actor), verb)
+		if e.Kind() != 'f' && g.Spent(actor) > 0 {
+			fmt.Fprintf(this.out, " (%g in the pot)", g.Spent(actor))
+		}
+		fmt.Fprintln(this.out)
+	case diff.Cards:
+		switch {
+		case g.Round == game.PreFlop:
+			fmt.Fprintf(this.out, "Your cards: %s\n", strings.Join(g.Holes, " "))
+		case g.Round < g.NumRounds():
+			fmt.Fprintf(this.out, "-- The %s: %s\n", roundName(g.Round), strings.Join(g.Board, " "))
+		default:
+			fmt.Fprintln(this.out, "-- The showdown")
+			for seat, hand := range g.Hands {
+				if len(hand) > 0 && g.Actions[seat] != 'f' {
+					fmt.Fprintf(this.out, "%s: %s\n", this.name(g, seat), strings.Join(hand, " "))
+				}
+			}
+		}
+	}
+}
+
+// table describes the table for the player's turn.
+func (this *human) table(g *game.Game) {
+	fmt.Fprintf(this.out, "\nYour cards: %s", strings.Join(g.Holes, " "))
+	if len(g.Board) > 0 {
+		fmt.Fprintf(this.out, "   Board: %s", strings.Join(g.Board, " "))
+	}
+	fmt.Fprintf(this.out, "   Pot: %g\n", g.Pot())
+	for seat := range g.Actions {
+		fmt.Fprintf(this.out, "  %-10s %6g in the pot", this.name(g, seat), g.Spent(seat))
+		if g.Actions[seat] == 'f' {
+			fmt.Fprint(this.out, " (folded)")
+		}
+		fmt.Fprintln(this.out)
+	}
+}
+
+// prompt returns the question asking for an action.
+func (this *human) prompt(g *game.Game) string {
+	var choices []string
+	legal := g.LegalActions()
+	if strings.Contains(legal, "f") {
+		choices = append(choices, "[f]old")
+	}
+	if g.CallAmt() > 0 {
+		choices = append(choices, fmt.Sprintf("[c]all %g", g.CallAmt()))
+	} else {
+		choices = append(choices, "[c]heck")
+	}
+	if strings.Contains(legal, "r") {
+		if g.Limit() {
+			choices = append(choices, fmt.Sprintf("[r]aise %g", g.RaiseAmt()))
+		} else {
+			choices = append(choices, fmt.Sprintf("[r]aise to %g-%g", g.MinRaise(), g.MaxRaise()))
+		}
+	}
+	return fmt.Sprintf("%s? ", strings.Join(choices, ", "))
+}
+
+// parse parses a typed action such as "call", "r", or "raise 500" into an
+// action for g, or returns an error explaining why it can't be taken.
+func parse(g *game.Game, s string) (string, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 {
+		return "", errors.New("Type an action.")
+	}
+	word, amount := fields[0], ""
+	if len(fields) > 1 {
+		amount = fields[1]
+	} else if i := strings.IndexAny(word, "0123456789"); i > 0 {
+		word, amount = word[:i], word[i:]
+	}
+	legal := g.LegalActions()
+	switch word {
+	case "f", "fold":
+		if !strings.Contains(legal, "f") {
+			return "", errors.New("There is nothing to call, so you can check for free.")
+		}
+		return "f", nil
+	case "c", "call":
+		return "c", nil
+	case "k", "check":
+		if g.CallAmt() > 0 {
+			return "", fmt.Errorf("There is %g to call, so you can't check.", g.CallAmt())
+		}
+		return "c", nil
+	case "r", "raise", "b", "bet":
+		if !strings.Contains(legal, "r") {
+			return "", errors.New("You can't raise now.")
+		}
+		if g.Limit() {
+			return "r", nil
+		}
+		to := g.MinRaise()
+		if amount != "" {
+			n, err := strconv.ParseFloat(amount, 64)
+			if err != nil {
+				return "", fmt.Errorf("%q is not an amount.", amount)
+			}
+			to = n
+		}
+		if to < g.MinRaise() || to > g.MaxRaise() {
+			return "", fmt.Errorf("You can raise to between %g and %g.", g.MinRaise(), g.MaxRaise())
+		}
+		return fmt.Sprintf("r%d", int(to)), nil
+	}
+	return "", fmt.Errorf("%q is not an action.", s)
+}
+
+// Play asks for an action until a legal one is typed. If the input ends, the
+// player checks or calls from then on.
+func (this *human) Play(g *game.Game) string {
+	this.show(g)
+	this.turn(g)
+	this.table(g)
+	for {
+		fmt.Fprint(this.out, this.prompt(g))
+		if !this.in.Scan() {
+			fmt.Fprintln(this.out)
+			return "c"
+		}
+		action, err := parse(g, this.in.Text())
+		if err == nil {
+			return action
+		}
+		fmt.Fprintln(this.out, err)
+	}
+}
+
+func (this *human) MatchEnded(err error) {
+	fmt.Fprintf(this.out, "\nThe match is over. You won %g in %d hands.\n", this.won, this.hand)
+}
+
+func main() {
+	rules := flag.String("rules", "2p-l", "What rules to use: "+
+		strings.Join(game.RulesNames(), ", ")+", or an ACPC .game file.")
+	flag.Parse()
+	r, err := game.ChooseRules(*rules)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	var engine diff.Engine
+	switch {
+	case flag.NArg() == 1 && strings.HasPrefix(flag.Arg(0), "http"):
+		engine, err = diff.DialHTTP(context.Background(), flag.Arg(0), diff.HTTPOptions{})
+	case flag.NArg() == 2:
+		engine, err = diff.DialACPC(context.Background(), net.JoinHostPort(flag.Arg(0), flag.Arg(1)),
+			diff.ACPCOptions{})
+	default:
+		log.Fatalln("usage: human [flags] host port, or human [flags] url")
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Printf("Playing %s. Type f, c, or r, or a raise such as \"r 500\".\n", r.Description())
+	if err := game.PlayWith(engine, r, newHuman(os.Stdin, os.Stdout)); err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"poker/game"
+	"poker/game/diff"
+)
+
+// newGame returns a game of rules after events.
+func newGame(test *testing.T, rules string, events ...interface{}) *game.Game {
+	g, err := game.NewGame(rules)
+	if err != nil {
+		test.Fatal(err)
+	}
+	for _, e := range events {
+		g.Update(e)
+	}
+	return g
+}
+
+func TestParse(test *testing.T) {
+	// The small blind is to act and faces a bet; the big blind may check.
+	facing := []interface{}{&diff.Players{Viewer: 1}, diff.Cards("|AsAd")}
+	free := []interface{}{&diff.Players{Viewer: 0}, diff.Cards("AsAd|"), diff.Action("c")}
+	tests := []struct {
+		rules  string
+		events []interface{}
+		typed  string
+		action string // The action expected, or "" for an error.
+	}{
+		{"2p-l", facing, "c", "c"},
+		{"2p-l", facing, "Call", "c"},
+		{"2p-l", facing, "f", "f"},
+		{"2p-l", facing, "k", ""},
+		{"2p-l", facing, "check", ""},
+		{"2p-l", facing, "r", "r"},
+		{"2p-l", facing, "raise 500", "r"},
+		{"2p-l", facing, "", ""},
+		{"2p-l", facing, "shove", ""},
+		{"2p-l", free, "k", "c"},
+		{"2p-l", free, "fold", ""},
+		{"2p-nl", facing, "r", "r200"},
+		{"2p-nl", facing, "r500", "r500"},
+		{"2p-nl", facing, "raise 500", "r500"},
+		{"2p-nl", facing, "bet 20000", "r20000"},
+		{"2p-nl", facing, "r100", ""},
+		{"2p-nl", facing, "r 30000", ""},
+		{"2p-nl", facing, "r lots", ""},
+		{"2p-nl", free, "check", "c"},
+		{"2p-nl", free, "b", "r200"},
+	}
+	for _, t := range tests {
+		g := newGame(test, t.rules, t.events...)
+		action, err := parse(g, t.typed)
+		if action != t.action || (err == nil) != (t.action != "") {
+			test.Errorf("%s: Expected %q to give %q, but got %q, %v\n", t.rules, t.typed, t.action, action, err)
+		}
+	}
+}
+
+func TestPrompt(test *testing.T) {
+	h := new(human)
+	g := newGame(test, "2p-nl", &diff.Players{Viewer: 1}, diff.Cards("|AsAd"))
+	if p := h.prompt(g); p != "[f]old, [c]all 50, [r]aise to 200-20000? " {
+		test.Errorf("Got the prompt %q facing the big blind\n", p)
+	}
+	g = newGame(test, "2p-l", &diff.Players{Viewer: 0}, diff.Cards("AsAd|"), diff.Action("c"))
+	if p := h.prompt(g); p != "[c]heck, [r]aise 10? " {
+		test.Errorf("Got the prompt %q with nothing to call\n", p)
+	}
+}
@@ -0,0 +1,191 @@
+// Package ev registers the ev strategy, a heuristic player that weighs the
+// expected value of each action, to be tuned into a strong baseline bot. Its
+// equity is worked out by simulation against the range of hands it expects
+// its opponents to hold, which is narrower once one of them has raised.
+//
+// A call is worth its share of the pot, plus a share of the pot expected to be
+// won later on the implied odds, less the chips it costs. A bet or raise is
+// worth the pot if every opponent folds, which each does with the fold
+// equity, and otherwise its share of the bigger pot less its cost. The player
+// bets or raises when that is worth the most and its equity is over the
+// raise threshold of the round, which goes up with each raise already made in
+// the round; it calls when its equity is over the pot odds and the call
+// threshold of the round, and otherwise checks or folds.
+package ev
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"poker/game"
+	"poker/game/diff"
+	"poker/strategy"
+)
+
+// The parameters and their defaults.
+var defaults = strategy.Params{
+	"trials":        "500",
+	"range":         "any",
+	"raise_range":   "22+,A2s+,K9s+,Q9s+,J9s+,T9s,A9o+,KTo+,QTo+,JTo",
+	"fold":          "0.2",
+	"implied":       "0",
+	"size":          "0.75",
+	"reraise":       "0.1",
+	"raise_preflop": "0.6",
+	"raise_flop":    "0.6",
+	"raise_turn":    "0.65",
+	"raise_river":   "0.7",
+	"call_preflop":  "0",
+	"call_flop":     "0",
+	"call_turn":     "0",
+	"call_river":    "0",
+}
+
+func init() {
+	strategy.Register("ev", "Chooses the action with the greatest EV from its equity "+
+		"against a modeled range, pot and implied odds, and fold equity. Parameters: "+
+		"trials, the simulations for the equity; range, the opponents' range, and "+
+		"raise_range, their range once they have raised, such as \"QQ+,AK\"; fold, "+
+		"the chance an opponent folds to a bet; implied, the share of the pot a call "+
+		"expects to win later; size, a no limit bet as a share of the pot; raise_ROUND, "+
+		"the least equity to bet in each round, and reraise, how much more for each "+
+		"raise made already; and call_ROUND, the least equity to call.", New)
+}
+
+// A player is an ev player.
+type player struct {
+	Name        string
+	trials      int
+	ranges      [2]Range // The opponents' range before and after they raise.
+	fold        float64
+	implied     float64
+	size        float64
+	reraise     float64
+	raise, call [len(game.RoundNames)]float64
+	rng         *rand.Rand
+	raised      bool // Whether an opponent has raised this hand.
+}
+
+// New returns an ev player called name.
+func New(name string, params strategy.Params) (game.Player, error) {
+	var known []string
+	for key := range defaults {
+		known = append(known, key)
+	}
+	if err := params.Check(known...); err != nil {
+		return nil, err
+	}
+	all := make(strategy.Params)
+	for k, v := range defaults {
+		all[k] = v
+	}
+	for k, v := range params {
+		all[k] = v
+	}
+	this := &player{Name: name, rng: rand.New(rand.NewSource(rand.Int63()))}
+	var err error
+	if this.trials, err = all.Int("trials", 0); err != nil {
+		return nil, err
+	}
+	if this.trials < 1 {
+		return nil, fmt.Errorf("ev: trials must be at least 1")
+	}
+	for i, key := range []string{"range", "raise_range"} {
+		if this.ranges[i], err = ParseRange(all[key]); err != nil {
+			return nil, err
+		}
+	}
+	for key, f := range map[string]*float64{
+		"fold": &this.fold, "implied": &this.implied, "size": &this.size, "reraise": &this.reraise,
+	} {
+		if *f, err = all.Float(key, 0); err != nil {
+			return nil, err
+		}
+	}
+	for i, round := range game.RoundNames {
+		if this.raise[i], err = all.Float("raise_"+round, 0); err != nil {
+			return nil, err
+		}
+		if this.call[i], err = all.Float("call_"+round, 0); err != nil {
+			return nil, err
+		}
+	}
+	return this, nil
+}
+
+// track notes who raised, so that their range can be narrowed.
+func (this *player) track(g *game.Game) {
+	switch e := g.Event.(type) {
+	case *diff.Players:
+		this.raised = false
+	case diff.Action:
+		if e.Kind() == 'r' && g.LastActor != g.Viewer {
+			this.raised = true
+		}
+	}
+}
+
+func (this *player) Observe(g *game.Game) {
+	this.track(g)
+}
+
+// equity returns the player's share of the pot against its opponents.
+func (this *player) equity(g *game.Game) float64 {
+	r := this.ranges[0]
+	if this.raised {
+		r = this.ranges[1]
+	}
+	var ranges []Range
+	for seat, a := range g.Actions {
+		if seat != g.Viewer && a != 'f' {
+			ranges = append(ranges, r)
+		}
+	}
+	boardCards := 0
+	for round := 0; round < g.NumRounds(); round++ {
+		boardCards += g.NumBoardCards(round)
+	}
+	return Equity(g.Holes, g.Board, ranges, g.Deck(), g.NumHoleCards(), boardCards, this.trials, this.rng)
+}
+
+// threshold returns the value of a parameter for round, using the river's for
+// any round after it.
+func threshold(values [len(game.RoundNames)]float64, round int) float64 {
+	if round >= len(values) {
+		round = len(values) - 1
+	}
+	return values[round]
+}
+
+func (this *player) Play(g *game.Game) string {
+	this.track(g)
+	e := this.equity(g)
+	pot, call := g.Pot(), g.CallAmt()
+	evCall := e*(pot+call) - call
+	if g.Round < g.NumRounds()-1 {
+		evCall += e * this.implied * pot
+	}
+	// The chips to put in to bet or raise, and the total raised to.
+	raise := g.RaiseAmt()
+	to := ""
+	if !g.Limit() {
+		total := g.Spent(g.Actor) + call + this.size*(pot+call)
+		total = math.Max(g.MinRaise(), math.Min(g.MaxRaise(), math.Floor(total)))
+		raise, to = total-g.Spent(g.Actor), fmt.Sprintf("%d", int(total))
+	}
+	opponents := g.NumActive() - 1
+	folds := math.Pow(this.fold, float64(opponents))
+	evRaise := folds*pot + (1-folds)*(e*(pot+raise+float64(opponents)*(raise-call))-raise)
+	legal := g.LegalActions()
+	switch {
+	case strings.Contains(legal, "r") &&
+		e >= threshold(this.raise, g.Round)+this.reraise*float64(g.Raises) &&
+		evRaise >= evCall && evRaise > 0:
+		return "r" + to
+	case call == 0 || (e >= threshold(this.call, g.Round) && evCall >= 0):
+		return "c"
+	}
+	return "f"
+}
@@ -0,0 +1,91 @@
+package ev
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"poker/game"
+	"poker/game/diff"
+	"poker/strategy"
+)
+
+func TestParseRange(test *testing.T) {
+	for s, n := range map[string]int{
+		"AhKh":        1,
+		"AJs":         4,
+		"77":          6,
+		"T9o":         12,
+		"54":          16,
+		"AJs+":        12,
+		"77+":         48,
+		"T9o-65o":     60,
+		"99-66":       24,
+		"QQ+,AQs+,AK": 38,
+		"QQ+ AK":      34,
+	} {
+		r, err := ParseRange(s)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if len(r) != n {
+			test.Errorf("Expected %d hands in %s, but got %d\n", n, s, len(r))
+		}
+	}
+	if r, err := ParseRange("any"); r != nil || err != nil {
+		test.Errorf("Expected any to be every hand, but got %v, %v\n", r, err)
+	}
+	for _, s := range []string{"AAs", "AKx", "T9o-65s", "T9o-64o", "AhAh", "1K"} {
+		if _, err := ParseRange(s); err == nil {
+			test.Errorf("Expected an error for %s\n", s)
+		}
+	}
+}
+
+func TestEquity(test *testing.T) {
+	r, _ := game.ChooseRules("2p-l")
+	rng := rand.New(rand.NewSource(1))
+	kings, _ := ParseRange("KK")
+	for _, c := range []struct {
+		r    Range
+		want float64
+	}{{nil, 0.85}, {kings, 0.82}} {
+		e := Equity([]string{"As", "Ad"}, nil, []Range{c.r}, r.Deck(), 2, 5, 5000, rng)
+		if math.Abs(e-c.want) > 0.02 {
+			test.Errorf("Expected aces to have %.2f equity against %v, but got %.3f\n", c.want, c.r, e)
+		}
+	}
+	// Against aces, a deuce can only win on a four flush.
+	aces, _ := ParseRange("AA")
+	e := Equity([]string{"2s", "2d"}, []string{"Ac", "Ks", "Qs"}, []Range{aces}, r.Deck(), 2, 5, 2000, rng)
+	if e > 0.05 {
+		test.Errorf("Expected deuces to be crushed by a set of aces, but got %.3f\n", e)
+	}
+}
+
+func TestPlay(test *testing.T) {
+	r, _ := game.ChooseRules("2p-nl")
+	p, err := strategy.New("ev", "Bob", strategy.Params{"trials": "1000"})
+	if err != nil {
+		test.Fatal(err)
+	}
+	g := game.NewGameRules(r)
+	for _, e := range []interface{}{&diff.Players{Viewer: 1}, diff.Cards("|AsAd")} {
+		g.Update(e)
+		p.Observe(g)
+	}
+	if a := p.Play(g); a[0] != 'r' {
+		test.Fatalf("Expected aces to raise, but got %q\n", a)
+	}
+	g = game.NewGameRules(r)
+	for _, e := range []interface{}{&diff.Players{Viewer: 0}, diff.Cards("7c2d|"), diff.Action("r20000")} {
+		g.Update(e)
+		p.Observe(g)
+	}
+	if a := p.Play(g); a != "f" {
+		test.Fatalf("Expected 72o to fold to an all in, but got %q\n", a)
+	}
+	if _, err := strategy.New("ev", "Bob", strategy.Params{"raise_flop": "high"}); err == nil {
+		test.Fatalf("Expected an error for a threshold that is not a number\n")
+	}
+}
@@ -0,0 +1,227 @@
+package ev
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"poker/cards"
+)
+
+// A Range is the set of two card hands a player may hold.
+type Range [][2]string
+
+// ParseRange parses a range written as in package equity, with the hands
+// separated by commas or spaces, such as "QQ+,AQs+,AK". Each hand may be
+//
+//	AhKh     Exactly those cards.
+//	AJs      Any ace and jack of the same suit, or of different suits with
+//	         AJo, or either with AJ.
+//	77       Any pair of sevens.
+//	AJs+     AJs, AQs, and AKs.
+//	77+      Any pair of sevens or better.
+//	T9o-65o  Any unsuited connector from 65o to T9o.
+//	99-66    Any pair from sixes to nines.
+//
+// An empty range, or "any", is every hand, and is returned as nil.
+func ParseRange(s string) (Range, error) {
+	var r Range
+	seen := make(map[[2]string]bool)
+	for _, hand := range strings.FieldsFunc(s, func(c rune) bool { return c == ',' || c == ' ' }) {
+		if hand == "any" {
+			return nil, nil
+		}
+		combos, err := parseHand(hand)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range combos {
+			if !seen[c] {
+				seen[c] = true
+				r = append(r, c)
+			}
+		}
+	}
+	return r, nil
+}
+
+// A class is a hand such as AJs without suits: the ranks of its cards, from
+// 0 for deuces, and 's' if suited, 'o' if not, or 0 for either.
+type class struct {
+	hi, lo int
+	suited byte
+}
+
+func parseClass(s string) (class, error) {
+	if len(s) < 2 || len(s) > 3 {
+		return class{}, fmt.Errorf("ev: invalid hand %q", s)
+	}
+	c := class{hi: strings.IndexByte(cards.Ranks, s[0]), lo: strings.IndexByte(cards.Ranks, s[1])}
+	if len(s) == 3 {
+		c.suited = s[2]
+	}
+	if c.hi < 0 || c.lo < 0 || (len(s) == 3 && c.suited != 's' && c.suited != 'o') ||
+		(c.hi == c.lo && c.suited != 0) {
+		return class{}, fmt.Errorf("ev: invalid hand %q", s)
+	}
+	if c.hi < c.lo {
+		c.hi, c.lo = c.lo, c.hi
+	}
+	return c, nil
+}
+
+// parseHand returns the combinations of cards in one hand of a range.
+func parseHand(s string) ([][2]string, error) {
+	if len(s) == 4 && strings.IndexByte(cards.Suits, s[1]) >= 0 && strings.IndexByte(cards.Suits, s[3]) >= 0 {
+		c1, c2 := s[:2], s[2:]
+		if strings.IndexByte(cards.Ranks, s[0]) < 0 || strings.IndexByte(cards.Ranks, s[2]) < 0 || c1 == c2 {
+			return nil, fmt.Errorf("ev: invalid hand %q", s)
+		}
+		return [][2]string{{c1, c2}}, nil
+	}
+	var classes []class
+	switch {
+	case strings.HasSuffix(s, "+"):
+		c, err := parseClass(s[:len(s)-1])
+		if err != nil {
+			return nil, err
+		}
+		if c.hi == c.lo {
+			for r := c.lo; r < len(cards.Ranks); r++ {
+				classes = append(classes, class{r, r, 0})
+			}
+		} else {
+			for r := c.lo; r < c.hi; r++ {
+				classes = append(classes, class{c.hi, r, c.suited})
+			}
+		}
+	case strings.Contains(s, "-"):
+		ends := strings.SplitN(s, "-", 2)
+		top, err := parseClass(ends[0])
+		if err != nil {
+			return nil, err
+		}
+		bottom, err := parseClass(ends[1])
+		if err != nil {
+			return nil, err
+		}
+		if bottom.hi > top.hi {
+			top, bottom = bottom, top
+		}
+		switch {
+		case top.suited != bottom.suited:
+			return nil, fmt.Errorf("ev: the ends of %q are of different kinds", s)
+		case top.hi == bottom.hi:
+			for r := bottom.lo; r <= top.lo; r++ {
+				classes = append(classes, class{top.hi, r, top.suited})
+			}
+		case top.hi-top.lo == bottom.hi-bottom.lo:
+			for d := 0; d <= top.hi-bottom.hi; d++ {
+				classes = append(classes, class{bottom.hi + d, bottom.lo + d, top.suited})
+			}
+		default:
+			return nil, fmt.Errorf("ev: the ends of %q are not in a line", s)
+		}
+	default:
+		c, err := parseClass(s)
+		if err != nil {
+			return nil, err
+		}
+		classes = append(classes, c)
+	}
+	var combos [][2]string
+	for _, c := range classes {
+		for s1 := range cards.Suits {
+			for s2 := range cards.Suits {
+				switch {
+				case c.hi == c.lo && s2 <= s1,
+					c.suited == 's' && s1 != s2,
+					c.suited == 'o' && s1 == s2:
+					continue
+				}
+				combos = append(combos, [2]string{
+					string([]byte{cards.Ranks[c.hi], cards.Suits[s1]}),
+					string([]byte{cards.Ranks[c.lo], cards.Suits[s2]}),
+				})
+			}
+		}
+	}
+	return combos, nil
+}
+
+// Equity returns the share of the pot that hole wins on average against
+// opponents who each hold a hand from their range, when the board is dealt
+// out to boardCards cards from deck. A nil range, or one with no hands that
+// can be dealt, is any cards. It plays trials random deals with rng.
+func Equity(hole, board []string, ranges []Range, deck []string, holeCards, boardCards, trials int,
+	rng *rand.Rand) float64 {
+	dead := make(map[string]bool)
+	for _, c := range append(append([]string{}, hole...), board...) {
+		dead[c] = true
+	}
+	live := make([]string, 0, len(deck))
+	for _, c := range deck {
+		if !dead[c] {
+			live = append(live, c)
+		}
+	}
+	// Drop the hands that can never be dealt.
+	possible := make([]Range, len(ranges))
+	for i, r := range ranges {
+		for _, h := range r {
+			if !dead[h[0]] && !dead[h[1]] {
+				possible[i] = append(possible[i], h)
+			}
+		}
+	}
+	var sum float64
+	used := make(map[string]bool)
+	for t := 0; t < trials; t++ {
+		for c := range used {
+			delete(used, c)
+		}
+		hands := make([][]string, len(ranges))
+		for i, r := range possible {
+			if len(r) == 0 || holeCards != 2 {
+				continue
+			}
+			// Hands that clash with the other opponents' are drawn again.
+			for try := 0; try < 10; try++ {
+				h := r[rng.Intn(len(r))]
+				if !used[h[0]] && !used[h[1]] {
+					hands[i] = []string{h[0], h[1]}
+					used[h[0]], used[h[1]] = true, true
+					break
+				}
+			}
+		}
+		rest := make([]string, 0, len(live))
+		for _, c := range live {
+			if !used[c] {
+				rest = append(rest, c)
+			}
+		}
+		rng.Shuffle(len(rest), func(i, j int) { rest[i], rest[j] = rest[j], rest[i] })
+		for i := range hands {
+			if hands[i] == nil {
+				hands[i], rest = rest[:holeCards], rest[holeCards:]
+			}
+		}
+		full := append(append([]string{}, board...), rest[:boardCards-len(board)]...)
+		mine := cards.Rank(append(append([]string{}, hole...), full...))
+		ties := 1
+		for _, h := range hands {
+			theirs := cards.Rank(append(append([]string{}, h...), full...))
+			if theirs > mine {
+				ties = 0
+				break
+			} else if theirs == mine {
+				ties++
+			}
+		}
+		if ties > 0 {
+			sum += 1 / float64(ties)
+		}
+	}
+	return sum / float64(trials)
+}
@@ -0,0 +1,73 @@
+// Package hs registers the 7cHS strategy, which plays to maximize EV based on
+// seven-card hand strength. Importing it loads the hand rank table of package
+// equity.
+package hs
+
+import (
+	"math/rand"
+	"strings"
+
+	"poker/equity"
+	"poker/game"
+	"poker/game/diff"
+	"poker/strategy"
+)
+
+func init() {
+	strategy.Register("7cHS", "Chooses the action with the greatest EV from the "+
+		"seven-card hand strength and the pot odds. Parameters: trials, the "+
+		"Monte-Carlo simulations for the hand strength, 1000 by default, or 0 to "+
+		"enumerate every hand.", New)
+}
+
+// stratPlayer chooses the action which has the greatest EV based on the 7cHS,
+// pot odds, and an implied call. An opponent call is included in the EV,
+// because in a two player game if the opponent does not call, the player will
+// outright win the pot.
+type stratPlayer struct {
+	Name   string
+	trials int
+	equity float64
+	rng    *rand.Rand // Its own source for the equity simulations.
+}
+
+// New returns a 7cHS player called name.
+func New(name string, params strategy.Params) (game.Player, error) {
+	if err := params.Check("trials"); err != nil {
+		return nil, err
+	}
+	trials, err := params.Int("trials", 1000)
+	if err != nil {
+		return nil, err
+	}
+	return &stratPlayer{Name: name, trials: trials, rng: rand.New(rand.NewSource(rand.Int63()))}, nil
+}
+
+func (this *stratPlayer) Observe(g *game.Game) {
+	if _, ok := g.Event.(diff.Cards); ok && g.Round != g.NumRounds() {
+		this.equity = equity.HandEquityRand(g.Holes, g.Board, this.trials, this.rng)
+	}
+}
+
+func (this *stratPlayer) Play(g *game.Game) string {
+	if _, ok := g.Event.(diff.Cards); ok {
+		this.equity = equity.HandEquityRand(g.Holes, g.Board, this.trials, this.rng)
+	}
+
+	max := 0.0 // Folding has EV = 0
+	action := "f"
+	c := g.CallAmt()
+	r := g.RaiseAmt()
+	pot := g.Pot()
+	if ev := (this.equity * (pot + c)) - c; ev >= max {
+		action = "c"
+		max = ev
+	}
+	if (this.equity*(pot+2*r-c))-r >= max {
+		action = "r"
+	}
+	if strings.Contains(g.LegalActions(), action) {
+		return action
+	}
+	return "c"
+}
@@ -0,0 +1,216 @@
+// Package strategy keeps a registry of player strategies, so that programs
+// such as hob2 can create players by name with parameters. A strategy is added
+// by registering it from the init function of its package, as package hs does,
+// and is then available to any program that imports that package.
+//
+// Parameters are given as "key=value" pairs, either separated by commas, as in
+//
+//	trials=500,aggression=1.5
+//
+// or one per line in a config file, where lines starting with "#" are
+// comments.
+package strategy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"poker/game"
+)
+
+// Params holds the parameters of a strategy by name.
+type Params map[string]string
+
+// ParseParams parses parameters such as "trials=500,aggression=1.5". A part
+// without "=" belongs to the value before it, so that values such as
+// "range=QQ+,AK" may have commas.
+func ParseParams(s string) (Params, error) {
+	p := make(Params)
+	var last string
+	for _, pair := range strings.Split(s, ",") {
+		if last != "" && !strings.Contains(pair, "=") && strings.TrimSpace(pair) != "" {
+			p[last] += "," + strings.TrimSpace(pair)
+			continue
+		}
+		if err := p.set(pair); err != nil {
+			return nil, err
+		}
+		if i := strings.Index(pair, "="); i > 0 {
+			last = strings.TrimSpace(pair[:i])
+		}
+	}
+	return p, nil
+}
+
+// ReadParams reads parameters from a config file with one "key=value" pair on
+// each line.
+func ReadParams(r io.Reader) (Params, error) {
+	p := make(Params)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		if err := p.set(text); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+	}
+	return p, scanner.Err()
+}
+
+// set sets the parameter in a "key=value" pair. Blank pairs are ignored.
+func (this Params) set(pair string) error {
+	pair = strings.TrimSpace(pair)
+	if pair == "" {
+		return nil
+	}
+	i := strings.Index(pair, "=")
+	if i < 1 {
+		return fmt.Errorf("strategy: %q is not a key=value pair", pair)
+	}
+	this[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+	return nil
+}
+
+// Check returns an error naming any parameter that is not one of known.
+func (this Params) Check(known ...string) error {
+	var unknown []string
+	for key := range this {
+		found := false
+		for _, k := range known {
+			found = found || k == key
+		}
+		if !found {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("strategy: unknown parameters %s, expected one of %s",
+			strings.Join(unknown, ", "), strings.Join(known, ", "))
+	}
+	return nil
+}
+
+// Float returns the parameter key as a number, or def if it is not set.
+func (this Params) Float(key string, def float64) (float64, error) {
+	s, ok := this[key]
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("strategy: %s=%q is not a number", key, s)
+	}
+	return f, nil
+}
+
+// Int returns the parameter key as an integer, or def if it is not set.
+func (this Params) Int(key string, def int) (int, error) {
+	s, ok := this[key]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("strategy: %s=%q is not an integer", key, s)
+	}
+	return n, nil
+}
+
+// A Factory creates a new player called name, configured by params. Each
+// player it returns must be independent of the others, so that they can play
+// at the same time.
+type Factory func(name string, params Params) (game.Player, error)
+
+type strategy struct {
+	about   string
+	factory Factory
+}
+
+var (
+	mu         sync.Mutex
+	strategies = make(map[string]strategy)
+)
+
+// Register makes a strategy available by name. about describes it and its
+// parameters. It panics if the name is already taken.
+func Register(name, about string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := strategies[name]; ok {
+		panic("strategy: Register called twice for " + name)
+	}
+	strategies[name] = strategy{about, f}
+}
+
+// Names returns the names of the registered strategies in order.
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	var names []string
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// About returns the description of a strategy.
+func About(name string) string {
+	mu.Lock()
+	defer mu.Unlock()
+	return strategies[name].about
+}
+
+// New creates a player called name that plays with the strategy strat.
+func New(strat, name string, params Params) (game.Player, error) {
+	mu.Lock()
+	s, ok := strategies[strat]
+	mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("strategy: %q was not found, expected one of %s",
+			strat, strings.Join(Names(), ", "))
+	}
+	return s.factory(name, params)
+}
+
+func init() {
+	Register("random", "Chooses uniformly at random from the legal actions. "+
+		"Parameters: seed, 0 for a random one.", newRandom)
+}
+
+// A randPlayer chooses an action uniformly at random from all legal actions
+// each turn.
+type randPlayer struct {
+	Name string
+	rng  *rand.Rand
+}
+
+func newRandom(name string, params Params) (game.Player, error) {
+	if err := params.Check("seed"); err != nil {
+		return nil, err
+	}
+	seed, err := params.Int("seed", 0)
+	if err != nil {
+		return nil, err
+	}
+	if seed == 0 {
+		seed = int(rand.Int63())
+	}
+	return &randPlayer{Name: name, rng: rand.New(rand.NewSource(int64(seed)))}, nil
+}
+
+func (_ *randPlayer) Observe(_ *game.Game) {}
+
+func (this *randPlayer) Play(g *game.Game) string {
+	a := g.LegalActions()
+	return string(a[this.rng.Intn(len(a))])
+}
@@ -0,0 +1,82 @@
+package strategy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"poker/game"
+	"poker/game/diff"
+)
+
+func TestParams(test *testing.T) {
+	p, err := ParseParams("trials=500, aggression = 1.5,")
+	if err != nil {
+		test.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, Params{"trials": "500", "aggression": "1.5"}) {
+		test.Fatalf("Expected two parameters, but got %v\n", p)
+	}
+	if n, err := p.Int("trials", 1000); n != 500 || err != nil {
+		test.Fatalf("Expected 500 trials, but got %d, %v\n", n, err)
+	}
+	if f, err := p.Float("bluff", 0.1); f != 0.1 || err != nil {
+		test.Fatalf("Expected the default bluff of 0.1, but got %g, %v\n", f, err)
+	}
+	if err := p.Check("trials"); err == nil {
+		test.Fatalf("Expected aggression to be an unknown parameter\n")
+	}
+	file, err := ReadParams(strings.NewReader("# Tuned\ntrials=500\n\naggression=1.5\n"))
+	if err != nil || !reflect.DeepEqual(file, p) {
+		test.Fatalf("Expected the file to give %v, but got %v, %v\n", p, file, err)
+	}
+	p, err = ParseParams("range=QQ+,AK,trials=5")
+	if err != nil || !reflect.DeepEqual(p, Params{"range": "QQ+,AK", "trials": "5"}) {
+		test.Fatalf("Expected the range to keep its comma, but got %v, %v\n", p, err)
+	}
+	if _, err := ReadParams(strings.NewReader("trials\n")); err == nil {
+		test.Fatalf("Expected an error for a line without a value\n")
+	}
+}
+
+func TestRegistry(test *testing.T) {
+	Register("test-caller", "Always checks or calls.", func(name string, params Params) (game.Player, error) {
+		return caller{}, params.Check()
+	})
+	defer func() {
+		// Remove the strategy, so that the test may run again.
+		mu.Lock()
+		delete(strategies, "test-caller")
+		mu.Unlock()
+	}()
+	if names := Names(); !reflect.DeepEqual(names, []string{"random", "test-caller"}) {
+		test.Fatalf("Expected the random and test-caller strategies, but got %v\n", names)
+	}
+	if _, err := New("test-caller", "Bob", Params{"x": "1"}); err == nil {
+		test.Fatalf("Expected an error for an unknown parameter\n")
+	}
+	if _, err := New("nope", "Bob", nil); err == nil {
+		test.Fatalf("Expected an error for an unknown strategy\n")
+	}
+	r, _ := game.ChooseRules("2p-l")
+	g := game.NewGameRules(r)
+	g.Update(&diff.Players{Viewer: 0})
+	g.Update(diff.Cards("AsAd|"))
+	for _, strat := range []string{"random", "test-caller"} {
+		p, err := New(strat, "Bob", Params{})
+		if err != nil {
+			test.Fatal(err)
+		}
+		if a := p.Play(g); !strings.Contains(g.LegalActions(), a) {
+			test.Fatalf("%s: Expected a legal action, but got %q\n", strat, a)
+		}
+	}
+}
+
+type caller struct{}
+
+func (_ caller) Observe(_ *game.Game) {}
+
+func (_ caller) Play(_ *game.Game) string {
+	return "c"
+}